@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/olekukonko/tablewriter"
-	"log"
 	"os"
 	"sync/atomic"
 	"time"
@@ -18,20 +17,199 @@ func printFinalSummary(queries []string, totalMessages uint64, duration time.Dur
 	fmt.Printf("################# RUNTIME STATS #################\n")
 	fmt.Printf("Total Duration %.3f Seconds\n", duration.Seconds())
 	fmt.Printf("Total Commands issued %d\n", totalCommands)
-	fmt.Printf("Total Errors %d ( %3.3f %%)\n", totalErrors, float64(totalErrors/totalCommands*100.0))
+	fmt.Printf("Total Errors %d ( %3.3f %%)\n", totalErrors, float64(totalErrors)/float64(totalCommands)*100.0)
 	fmt.Printf("Throughput summary: %.0f requests per second\n", messageRate)
 	renderGraphResultSetTable(queries, writer, "## Overall FalkorDB resultset stats table\n")
 	renderGraphInternalExecutionTimeTable(queries, writer, "## Overall FalkorDB Internal Execution Time summary table\n", serverSidePerQueryGraphInternalTimeOverallLatencies, serverSideAllQueriesGraphInternalTimeOverallLatencies)
-	renderTable(queries, writer, "## Overall Client Latency summary table\n", true, true, errorsPerQuery, duration, clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies)
+	renderTable(queries, writer, "## Overall Client Latency summary table ( successful requests )\n", true, true, totalCommandsPerQuery, errorsPerQuery, duration, clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies)
+	if totalErrors > 0 {
+		renderTable(queries, writer, "## Overall Client Latency summary table ( failed requests )\n", false, true, totalCommandsPerQuery, errorsPerQuery, duration, clientSidePerQueryFailedLatencies, clientSideAllQueriesFailedLatencies)
+		renderErrorClassTable(queries, writer, "## Errors by class table\n")
+	}
+	if CountTotal(retryAttemptsPerQuery) > 0 || CountTotal(expectedErrorsPerQuery) > 0 {
+		renderRetriesTable(queries, writer, "## Expected errors and retries summary table\n")
+	}
+	renderPlanCacheTable(queries, writer, "## Execution plan cache table\n")
+	if totalValidationStats.Checked > 0 {
+		renderValidationTable(queries, writer, "## Result set validation table\n")
+	}
+}
+
+func printServerStatsSummary(summary ServerStatsSummary, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Metric", "Before", "After", "Delta"}
+	data := [][]string{
+		{"Used memory (bytes)", fmt.Sprintf("%d", summary.Before.UsedMemory), fmt.Sprintf("%d", summary.After.UsedMemory), fmt.Sprintf("%.0f", summary.Deltas["UsedMemory"])},
+		{"Used memory RSS (bytes)", fmt.Sprintf("%d", summary.Before.UsedMemoryRss), fmt.Sprintf("%d", summary.After.UsedMemoryRss), fmt.Sprintf("%.0f", summary.Deltas["UsedMemoryRss"])},
+		{"Used CPU (seconds)", fmt.Sprintf("%.3f", summary.Before.UsedCpuSys+summary.Before.UsedCpuUser), fmt.Sprintf("%.3f", summary.After.UsedCpuSys+summary.After.UsedCpuUser), fmt.Sprintf("%.3f", summary.Deltas["UsedCpu"])},
+		{"Commands processed", fmt.Sprintf("%d", summary.Before.TotalCommandsProcessed), fmt.Sprintf("%d", summary.After.TotalCommandsProcessed), fmt.Sprintf("%.0f", summary.Deltas["TotalCommandsProcessed"])},
+		{"Keys", fmt.Sprintf("%d", summary.Before.Keys), fmt.Sprintf("%d", summary.After.Keys), fmt.Sprintf("%.0f", summary.Deltas["Keys"])},
+	}
+	if summary.Before.Nodes != nil && summary.After.Nodes != nil {
+		data = append(data, []string{"Nodes", fmt.Sprintf("%d", *summary.Before.Nodes), fmt.Sprintf("%d", *summary.After.Nodes), fmt.Sprintf("%.0f", summary.Deltas["Nodes"])})
+	}
+	if summary.Before.Edges != nil && summary.After.Edges != nil {
+		data = append(data, []string{"Edges", fmt.Sprintf("%d", *summary.Before.Edges), fmt.Sprintf("%d", *summary.After.Edges), fmt.Sprintf("%.0f", summary.Deltas["Edges"])})
+	}
+	if summary.Before.GraphMemoryUsageMB != nil && summary.After.GraphMemoryUsageMB != nil {
+		data = append(data, []string{"Graph memory usage (MB)", fmt.Sprintf("%.3f", *summary.Before.GraphMemoryUsageMB), fmt.Sprintf("%.3f", *summary.After.GraphMemoryUsageMB), fmt.Sprintf("%.3f", summary.Deltas["GraphMemoryUsageMB"])})
+	}
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+	fmt.Fprintf(writer, "Used memory growth per million write queries: %.0f bytes\n", summary.MemoryGrowthPerMillionWrites)
+}
+
+func printClientStatsSummary(summary ClientStatsSummary, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	fmt.Fprintf(writer, "CPU %.1f%% average, %.1f%% peak ( %.0f%% available ), %d goroutines peak\n", summary.AvgCpuPercent, summary.PeakCpuPercent, summary.AvailableCpuPercent, summary.PeakGoroutines)
+	fmt.Fprintf(writer, "GC pauses %.0f, p50 %.3f ms, p99 %.3f ms, max %.3f ms\n", summary.GCPauses["count"], summary.GCPauses["q50"], summary.GCPauses["q99"], summary.GCPauses["q100"])
+	fmt.Fprintf(writer, "Workers blocked recording their datapoints for %.3f seconds ( %.2f%% of their time )\n", summary.StatsBlockedSeconds, summary.StatsBlockedRatio*100.0)
+	for _, warning := range summary.Warnings {
+		fmt.Fprintf(writer, "WARNING: the benchmark client is saturated, %s. The reported latencies include client side delays and are not trustworthy.\n", warning)
+	}
+}
+
+func printProcessStatsSummary(summary *ProcessStatsSummary, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Metric", "Peak", "Average"}
+	data := [][]string{
+		{"CPU (%)", fmt.Sprintf("%.1f", summary.PeakCpuPercent), fmt.Sprintf("%.1f", summary.AvgCpuPercent)},
+		{"RSS (bytes)", fmt.Sprintf("%d", summary.PeakRssBytes), fmt.Sprintf("%.0f", summary.AvgRssBytes)},
+		{"Threads", fmt.Sprintf("%d", summary.PeakThreads), fmt.Sprintf("%.1f", summary.AvgThreads)},
+	}
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+	fmt.Fprintf(writer, "CPU seconds %.3f ( %.4f per 1k queries ), context switches %d, read %d bytes, written %d bytes ( sampled from %s )\n",
+		summary.CpuSeconds, summary.CpuSecondsPer1kQueries, summary.ContextSwitches, summary.ReadBytes, summary.WriteBytes, summary.Source)
+}
+
+func printIngestionSummary(stats *IngestionStats, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Step", "File", "Entities", "Batches", "Duration (s)", "Entities/sec", "Batch p50(ms)", "Batch p99(ms)"}
+	data := make([][]string, 0, len(stats.Steps)+1)
+	for _, step := range stats.Steps {
+		data = append(data, []string{step.Name, step.File, fmt.Sprintf("%d", step.Entities), fmt.Sprintf("%d", step.Batches), fmt.Sprintf("%.3f", float64(step.DurationMillis)/1000.0),
+			fmt.Sprintf("%.0f", step.EntitiesPerSec), fmt.Sprintf("%.3f", step.BatchLatencies["q50"]), fmt.Sprintf("%.3f", step.BatchLatencies["q99"])})
+	}
+	data = append(data, []string{"Total", "", fmt.Sprintf("%d", stats.Entities), "", fmt.Sprintf("%.3f", float64(stats.DurationMillis)/1000.0), fmt.Sprintf("%.0f", stats.EntitiesPerSec), "", ""})
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+}
+
+func renderPlanCacheTable(queries []string, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Query", "Executions", "Cached executions", "Cached %", "Uncached internal p50(ms)", "Cached internal p50(ms)", "Uncached client p50(ms)", "Cached client p50(ms)"}
+	data := make([][]string, len(queries)+1)
+	i := 0
+	for i = 0; i < len(queries); i++ {
+		data[i] = planCacheTableLine(queries[i], cachedExecutionsPerQuery[i], clientSidePerQueryUncachedLatencies[i], serverSidePerQueryUncachedLatencies[i], clientSidePerQueryCachedLatencies[i], serverSidePerQueryCachedLatencies[i])
+	}
+	data[i] = planCacheTableLine("Total", CountTotal(cachedExecutionsPerQuery), clientSideAllQueriesUncachedLatencies, serverSideAllQueriesUncachedLatencies, clientSideAllQueriesCachedLatencies, serverSideAllQueriesCachedLatencies)
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+}
+
+func planCacheTableLine(query string, cachedExecutions uint64, uncachedClient, uncachedInternal, cachedClient, cachedInternal *hdrhistogram.Histogram) []string {
+	stats := NewPlanCacheStats(cachedExecutions, uncachedClient, uncachedInternal, cachedClient, cachedInternal)
+	return []string{query, fmt.Sprintf("%d", stats.Executions), fmt.Sprintf("%d", stats.CachedExecutions), fmt.Sprintf("%.3f", stats.CachedRatio*100.0),
+		fmt.Sprintf("%.3f", stats.UncachedGraphInternalLatencies["q50"]), fmt.Sprintf("%.3f", stats.CachedGraphInternalLatencies["q50"]),
+		fmt.Sprintf("%.3f", stats.UncachedClientLatencies["q50"]), fmt.Sprintf("%.3f", stats.CachedClientLatencies["q50"])}
+}
+
+func renderValidationTable(queries []string, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Query", "Checked results", "Violations", "Violations %", "First violation"}
+	data := make([][]string, 0)
+	for i, stats := range append(append([]*ValidationStats{}, validationStatsPerQuery...), totalValidationStats) {
+		label := "Total"
+		if i < len(queries) {
+			label = queries[i]
+		}
+		if stats.Checked == 0 {
+			continue
+		}
+		firstViolation := ""
+		if len(stats.Samples) > 0 {
+			firstViolation = stats.Samples[0]
+		}
+		data = append(data, []string{label, fmt.Sprintf("%d", stats.Checked), fmt.Sprintf("%d", stats.Violations), fmt.Sprintf("%.3f", float64(stats.Violations)/float64(stats.Checked)*100.0), firstViolation})
+	}
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
 }
 
-func renderTable(queries []string, writer *os.File, tableTitle string, includeCalls bool, includeErrors bool, errorSlice []uint64, duration time.Duration, detailedHistogram []*hdrhistogram.Histogram, overallHistogram *hdrhistogram.Histogram) {
+func renderRetriesTable(queries []string, writer *os.File, tableTitle string) {
 	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Query", "Expected errors", "Retry attempts", "Failed retries", "Recovered requests", "Failed requests", "Retry p50 latency(ms)", "Retry p99 latency(ms)"}
 	data := make([][]string, len(queries)+1)
+	i := 0
+	for i = 0; i < len(queries); i++ {
+		data[i] = []string{queries[i], fmt.Sprintf("%d", expectedErrorsPerQuery[i]), fmt.Sprintf("%d", retryAttemptsPerQuery[i]), fmt.Sprintf("%d", retryErrorsPerQuery[i]), fmt.Sprintf("%d", recoveredRequestsPerQuery[i]), fmt.Sprintf("%d", failedRequestsPerQuery[i]),
+			fmt.Sprintf("%.3f", float64(histogramOrEmpty(clientSidePerQueryRetryLatencies[i]).ValueAtQuantile(50.0))/1000.0), fmt.Sprintf("%.3f", float64(histogramOrEmpty(clientSidePerQueryRetryLatencies[i]).ValueAtQuantile(99.0))/1000.0)}
+	}
+	data[i] = []string{"Total", fmt.Sprintf("%d", CountTotal(expectedErrorsPerQuery)), fmt.Sprintf("%d", CountTotal(retryAttemptsPerQuery)), fmt.Sprintf("%d", CountTotal(retryErrorsPerQuery)), fmt.Sprintf("%d", CountTotal(recoveredRequestsPerQuery)), fmt.Sprintf("%d", CountTotal(failedRequestsPerQuery)),
+		fmt.Sprintf("%.3f", float64(histogramOrEmpty(clientSideAllQueriesRetryLatencies).ValueAtQuantile(50.0))/1000.0), fmt.Sprintf("%.3f", float64(histogramOrEmpty(clientSideAllQueriesRetryLatencies).ValueAtQuantile(99.0))/1000.0)}
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+}
+
+func renderErrorClassTable(queries []string, writer *os.File, tableTitle string) {
+	fmt.Fprintf(writer, tableTitle)
+	initialHeader := []string{"Query", "Error class", "Count", "First seen", "Last seen", "First sample"}
+	data := make([][]string, 0)
 	for i := 0; i < len(queries); i++ {
-		insertTableLine(queries[i], data, i, includeCalls, includeErrors, errorSlice, duration, detailedHistogram[i])
+		for _, class := range errorClasses {
+			classStats, ok := errorClassStatsPerQuery[i][class]
+			if !ok {
+				continue
+			}
+			sample := ""
+			if len(classStats.Samples) > 0 {
+				sample = classStats.Samples[0]
+			}
+			data = append(data, []string{queries[i], class, fmt.Sprintf("%d", classStats.Count),
+				time.UnixMilli(classStats.FirstSeen).Format(time.RFC3339), time.UnixMilli(classStats.LastSeen).Format(time.RFC3339), sample})
+		}
 	}
-	insertTableLine("Total", data, len(queries), includeCalls, includeErrors, errorSlice, duration, overallHistogram)
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(initialHeader)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+}
+
+func renderTable(queries []string, writer *os.File, tableTitle string, includeCalls bool, includeErrors bool, commandsSlice []uint64, errorSlice []uint64, duration time.Duration, detailedHistogram []*hdrhistogram.Histogram, overallHistogram *hdrhistogram.Histogram) {
+	fmt.Fprintf(writer, tableTitle)
+	data := make([][]string, len(queries)+1)
+	for i := 0; i < len(queries); i++ {
+		insertTableLine(queries[i], data, i, includeCalls, includeErrors, commandsSlice, errorSlice, duration, detailedHistogram[i])
+	}
+	insertTableLine("Total", data, len(queries), includeCalls, includeErrors, commandsSlice, errorSlice, duration, overallHistogram)
 	table := tablewriter.NewWriter(writer)
 	initialHeader := []string{"Query"}
 	if includeCalls {
@@ -75,15 +253,21 @@ func renderGraphInternalExecutionTimeTable(queries []string, writer *os.File, ta
 	table.Render()
 }
 
-func insertTableLine(queryName string, data [][]string, i int, includeCalls, includeErrors bool, errorsSlice []uint64, duration time.Duration, histogram *hdrhistogram.Histogram) {
+func insertTableLine(queryName string, data [][]string, i int, includeCalls, includeErrors bool, commandsSlice []uint64, errorsSlice []uint64, duration time.Duration, histogram *hdrhistogram.Histogram) {
 	data[i] = make([]string, 5)
 	latencyPadding := 0
 	data[i][0] = queryName
 	if includeCalls {
-		totalCmds := histogram.TotalCount()
+		var totalCmds uint64
+		// total commands, including the failed ones
+		if i == (len(data) - 1) {
+			totalCmds = totalCommands
+		} else {
+			totalCmds = commandsSlice[i]
+		}
 		cmdRate := float64(totalCmds) / duration.Seconds()
 		data[i][1] = fmt.Sprintf("%.f", cmdRate)
-		data[i][2] = fmt.Sprintf("%d", histogram.TotalCount())
+		data[i][2] = fmt.Sprintf("%d", totalCmds)
 		data[i] = append(data[i], "", "")
 		latencyPadding += 2
 
@@ -108,11 +292,11 @@ func insertTableLine(queryName string, data [][]string, i int, includeCalls, inc
 
 func renderGraphResultSetTable(queries []string, writer *os.File, tableTitle string) {
 	fmt.Fprintf(writer, tableTitle)
-	initialHeader := []string{"Query", "Nodes created", "Nodes deleted", "Labels added", "Properties set", " Relationships created", " Relationships deleted"}
+	initialHeader := []string{"Query", "Nodes created", "Nodes deleted", "Labels added", "Properties set", " Relationships created", " Relationships deleted", "Empty resultsets", "Rows returned", "Rows p50", "Rows p99", "Reply bytes p50", "Reply bytes p99"}
 	data := make([][]string, len(queries)+1)
 	i := 0
 	for i = 0; i < len(queries); i++ {
-		data[i] = make([]string, 7)
+		data[i] = make([]string, 13)
 		data[i][0] = queries[i]
 		data[i][1] = fmt.Sprintf("%d", totalNodesCreatedPerQuery[i])
 		data[i][2] = fmt.Sprintf("%d", totalNodesDeletedPerQuery[i])
@@ -120,8 +304,14 @@ func renderGraphResultSetTable(queries []string, writer *os.File, tableTitle str
 		data[i][4] = fmt.Sprintf("%d", totalPropertiesSetPerQuery[i])
 		data[i][5] = fmt.Sprintf("%d", totalRelationshipsCreatedPerQuery[i])
 		data[i][6] = fmt.Sprintf("%d", totalRelationshipsDeletedPerQuery[i])
+		data[i][7] = fmt.Sprintf("%d", emptyResultsetsPerQuery[i])
+		data[i][8] = fmt.Sprintf("%d", totalRowsPerQuery[i])
+		data[i][9] = fmt.Sprintf("%d", rowsPerRequestPerQuery[i].ValueAtQuantile(50.0))
+		data[i][10] = fmt.Sprintf("%d", rowsPerRequestPerQuery[i].ValueAtQuantile(99.0))
+		data[i][11] = fmt.Sprintf("%d", replyBytesPerRequestPerQuery[i].ValueAtQuantile(50.0))
+		data[i][12] = fmt.Sprintf("%d", replyBytesPerRequestPerQuery[i].ValueAtQuantile(99.0))
 	}
-	data[i] = make([]string, 7)
+	data[i] = make([]string, 13)
 	data[i][0] = "Total"
 	data[i][1] = fmt.Sprintf("%d", totalNodesCreated)
 	data[i][2] = fmt.Sprintf("%d", totalNodesDeleted)
@@ -129,6 +319,12 @@ func renderGraphResultSetTable(queries []string, writer *os.File, tableTitle str
 	data[i][4] = fmt.Sprintf("%d", totalPropertiesSet)
 	data[i][5] = fmt.Sprintf("%d", totalRelationshipsCreated)
 	data[i][6] = fmt.Sprintf("%d", totalRelationshipsDeleted)
+	data[i][7] = fmt.Sprintf("%d", totalEmptyResultsets)
+	data[i][8] = fmt.Sprintf("%d", CountTotal(totalRowsPerQuery))
+	data[i][9] = fmt.Sprintf("%d", rowsPerRequestAllQueries.ValueAtQuantile(50.0))
+	data[i][10] = fmt.Sprintf("%d", rowsPerRequestAllQueries.ValueAtQuantile(99.0))
+	data[i][11] = fmt.Sprintf("%d", replyBytesPerRequestAllQueries.ValueAtQuantile(50.0))
+	data[i][12] = fmt.Sprintf("%d", replyBytesPerRequestAllQueries.ValueAtQuantile(99.0))
 	table := tablewriter.NewWriter(writer)
 	table.SetHeader(initialHeader)
 	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
@@ -137,21 +333,23 @@ func renderGraphResultSetTable(queries []string, writer *os.File, tableTitle str
 	table.Render()
 }
 
-func updateCLI(startTime time.Time, tick *time.Ticker, c chan os.Signal, messageLimit uint64, loop bool, panicChannel chan bool) bool {
+func updateCLI(startTime time.Time, tick *time.Ticker, c chan os.Signal, messageLimit uint64, loop bool, stopper *runStopper, queryIds []string, queryLabels []string, exporter *TimeSeriesExporter, clientSampler *clientStatsSampler) bool {
 
 	start := startTime
 	prevTime := startTime
 	prevMessageCount := uint64(0)
+	prevErrorCount := uint64(0)
+	prevCommandsPerQuery := make([]uint64, len(queryIds))
+	prevErrorsPerQuery := make([]uint64, len(queryIds))
 	var currentCmds uint64
 	var currentErrs uint64
 	var messageRateTs []float64
 	fmt.Printf("%26s %7s %25s %25s %7s %25s %25s %26s\n", "Test time", " ", "Total Commands", "Total Errors", "", "Command Rate", "Client p50 with RTT(ms)", "Graph Internal Time p50 (ms)")
 	for {
 		select {
-		case <-panicChannel:
-			{
-				log.Panic("Panic in worker routine, aborting")
-			}
+		case <-stopper.Done():
+			fmt.Printf("\nAborting benchmark: %s\n", stopper.Reason())
+			return false
 		case <-tick.C:
 			{
 				now := time.Now()
@@ -175,12 +373,18 @@ func updateCLI(startTime time.Time, tick *time.Ticker, c chan os.Signal, message
 				if currentCmds != 0 {
 					messageRateTs = append(messageRateTs, messageRate)
 				}
+				if exporter != nil {
+					exporter.Export(now, collectTickSamples(queryIds, queryLabels, took, prevCommandsPerQuery, prevErrorsPerQuery, currentCmds-prevMessageCount, currentErrs-prevErrorCount))
+				}
+				clientSampler.Sample(now)
 				prevMessageCount = currentCmds
+				prevErrorCount = currentErrs
 				prevTime = now
 
 				fmt.Printf("%25.0fs %s %25d %25d [%3.1f%%] %25.2f %19.3f (%3.3f) %20.3f (%3.3f)\t", time.Since(start).Seconds(), completionPercentStr, currentCmds, currentErrs, errorPercent, messageRate, instantP50, p50, instantP50RunTimeGraph, p50RunTimeGraph)
 				fmt.Printf("\r")
 				if messageLimit > 0 && currentCmds >= messageLimit && !loop {
+					stopper.Stop(stopReasonCompleted)
 					return true
 				}
 				// The locks we acquire here do not affect the clients
@@ -190,6 +394,7 @@ func updateCLI(startTime time.Time, tick *time.Ticker, c chan os.Signal, message
 
 		case <-c:
 			fmt.Println("\nReceived Ctrl-c - shutting down cli updater go-routine")
+			stopper.Stop(stopReasonInterrupted)
 			return false
 		}
 	}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	runtimepprof "runtime/pprof"
+)
+
+// startPprofServer exposes the net/http/pprof handlers of the benchmark client, on a dedicated mux
+func startPprofServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("pprof endpoint on %s stopped: %v", addr, err)
+		}
+	}()
+	fmt.Printf("Exposing the client pprof endpoints on http://%s/debug/pprof/\n", addr)
+	return server
+}
+
+// startCPUProfile profiles the benchmark client into the given file. The returned function stops the profile.
+func startCPUProfile(cpuProfileFile string) func() {
+	file, err := os.Create(cpuProfileFile)
+	if err != nil {
+		log.Panicf("Could not create the cpu profile file: %v", err)
+	}
+	if err = runtimepprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		log.Panicf("Could not start the cpu profile: %v", err)
+	}
+	return func() {
+		runtimepprof.StopCPUProfile()
+		file.Close()
+		fmt.Printf("Saved the client cpu profile to %s\n", cpuProfileFile)
+	}
+}
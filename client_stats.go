@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// the client is considered saturated above these thresholds
+	clientSaturationCpuRatio          = 0.9
+	clientSaturationStatsBlockedRatio = 0.05
+	clientSaturationGCPauseP99Millis  = 10.0
+)
+
+var clientRuntimeMetrics = []metrics.Sample{
+	{Name: "/gc/pauses:seconds"},
+	{Name: "/sched/goroutines:goroutines"},
+	{Name: "/memory/classes/heap/objects:bytes"},
+}
+
+// ClientStatsSample holds the benchmark client own resource usage for one CLI tick
+type ClientStatsSample struct {
+	TimestampMillis int64 `json:"TimestampMillis"`
+	// Cpu usage since the previous sample, 100 being one full core
+	CpuPercent float64 `json:"CpuPercent"`
+	Goroutines int64   `json:"Goroutines"`
+	HeapBytes  uint64  `json:"HeapBytes"`
+	// GC pauses since the previous sample
+	GCPauses uint64 `json:"GCPauses"`
+	// Time the workers spent blocked recording datapoints, since the previous sample
+	StatsBlockedMillis float64 `json:"StatsBlockedMillis"`
+}
+
+// ClientStatsSummary tells whether the benchmark client itself was the bottleneck of the measured phase
+type ClientStatsSummary struct {
+	CpuSeconds     float64 `json:"CpuSeconds"`
+	AvgCpuPercent  float64 `json:"AvgCpuPercent"`
+	PeakCpuPercent float64 `json:"PeakCpuPercent"`
+	// GOMAXPROCS * 100
+	AvailableCpuPercent float64 `json:"AvailableCpuPercent"`
+	PeakGoroutines      int64   `json:"PeakGoroutines"`
+	// Count and quantiles, in milliseconds, of the GC pauses
+	GCPauses map[string]float64 `json:"GCPauses"`
+	// Time the workers spent blocked recording datapoints, and its ratio over the workers running time
+	StatsBlockedSeconds float64 `json:"StatsBlockedSeconds"`
+	StatsBlockedRatio   float64 `json:"StatsBlockedRatio"`
+	// When saturated, the reported latencies include client side delays
+	Saturated bool     `json:"Saturated"`
+	Warnings  []string `json:"Warnings"`
+}
+
+// clientStatsSampler samples the benchmark client itself. It's only used from the CLI go-routine.
+type clientStatsSampler struct {
+	pid int
+	// /proc is only available on Linux, the cpu usage is left empty elsewhere
+	procAvailable    bool
+	startCpuSeconds  float64
+	lastCpuSeconds   float64
+	lastTimestamp    time.Time
+	startGCPauses    *metrics.Float64Histogram
+	lastGCPauses     uint64
+	lastBlockedNanos uint64
+	peakCpuPercent   float64
+	peakGoroutines   int64
+	samples          map[int64]interface{}
+}
+
+func newClientStatsSampler(now time.Time) *clientStatsSampler {
+	s := &clientStatsSampler{pid: os.Getpid(), lastTimestamp: now, samples: map[int64]interface{}{}}
+	if sample, err := sampleProcStats(s.pid); err == nil {
+		s.procAvailable = true
+		s.startCpuSeconds = sample.CpuSeconds
+		s.lastCpuSeconds = sample.CpuSeconds
+	}
+	metrics.Read(clientRuntimeMetrics)
+	if clientRuntimeMetrics[0].Value.Kind() == metrics.KindFloat64Histogram {
+		s.startGCPauses = copyFloat64Histogram(clientRuntimeMetrics[0].Value.Float64Histogram())
+		s.lastGCPauses = histogramCount(s.startGCPauses)
+	}
+	s.lastBlockedNanos = atomic.LoadUint64(&datapointsRecordBlockedNanos)
+	return s
+}
+
+func (s *clientStatsSampler) Sample(now time.Time) ClientStatsSample {
+	sample := ClientStatsSample{TimestampMillis: now.UnixMilli()}
+	elapsed := now.Sub(s.lastTimestamp).Seconds()
+	if s.procAvailable {
+		if procSample, err := sampleProcStats(s.pid); err == nil && elapsed > 0 {
+			sample.CpuPercent = (procSample.CpuSeconds - s.lastCpuSeconds) / elapsed * 100.0
+			s.lastCpuSeconds = procSample.CpuSeconds
+		}
+	}
+	metrics.Read(clientRuntimeMetrics)
+	if clientRuntimeMetrics[0].Value.Kind() == metrics.KindFloat64Histogram {
+		pauses := histogramCount(clientRuntimeMetrics[0].Value.Float64Histogram())
+		sample.GCPauses = pauses - s.lastGCPauses
+		s.lastGCPauses = pauses
+	}
+	if clientRuntimeMetrics[1].Value.Kind() == metrics.KindUint64 {
+		sample.Goroutines = int64(clientRuntimeMetrics[1].Value.Uint64())
+	} else {
+		sample.Goroutines = int64(runtime.NumGoroutine())
+	}
+	if clientRuntimeMetrics[2].Value.Kind() == metrics.KindUint64 {
+		sample.HeapBytes = clientRuntimeMetrics[2].Value.Uint64()
+	}
+	blockedNanos := atomic.LoadUint64(&datapointsRecordBlockedNanos)
+	sample.StatsBlockedMillis = float64(blockedNanos-s.lastBlockedNanos) / 1e6
+	s.lastBlockedNanos = blockedNanos
+	s.lastTimestamp = now
+
+	if sample.CpuPercent > s.peakCpuPercent {
+		s.peakCpuPercent = sample.CpuPercent
+	}
+	if sample.Goroutines > s.peakGoroutines {
+		s.peakGoroutines = sample.Goroutines
+	}
+	s.samples[sample.TimestampMillis] = sample
+	return sample
+}
+
+// Summary must be called once the workers are done. duration is the measured phase duration.
+func (s *clientStatsSampler) Summary(duration time.Duration, clients uint64) ClientStatsSummary {
+	summary := ClientStatsSummary{AvailableCpuPercent: float64(runtime.GOMAXPROCS(0)) * 100.0, PeakCpuPercent: s.peakCpuPercent, PeakGoroutines: s.peakGoroutines, Warnings: []string{}}
+	if s.procAvailable {
+		if procSample, err := sampleProcStats(s.pid); err == nil {
+			summary.CpuSeconds = procSample.CpuSeconds - s.startCpuSeconds
+			summary.AvgCpuPercent = summary.CpuSeconds / duration.Seconds() * 100.0
+		}
+	}
+	summary.GCPauses = map[string]float64{"count": 0, "q50": 0, "q99": 0, "q100": 0}
+	metrics.Read(clientRuntimeMetrics)
+	if s.startGCPauses != nil && clientRuntimeMetrics[0].Value.Kind() == metrics.KindFloat64Histogram {
+		pauses := subtractFloat64Histogram(clientRuntimeMetrics[0].Value.Float64Histogram(), s.startGCPauses)
+		summary.GCPauses["count"] = float64(histogramCount(pauses))
+		summary.GCPauses["q50"] = histogramQuantile(pauses, 0.5) * 1000.0
+		summary.GCPauses["q99"] = histogramQuantile(pauses, 0.99) * 1000.0
+		summary.GCPauses["q100"] = histogramQuantile(pauses, 1.0) * 1000.0
+	}
+	summary.StatsBlockedSeconds = float64(atomic.LoadUint64(&datapointsRecordBlockedNanos)) / 1e9
+	if clients > 0 && duration > 0 {
+		summary.StatsBlockedRatio = summary.StatsBlockedSeconds / (duration.Seconds() * float64(clients))
+	}
+
+	if summary.AvgCpuPercent >= clientSaturationCpuRatio*summary.AvailableCpuPercent {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("the client used %.0f%% cpu out of the %.0f%% available", summary.AvgCpuPercent, summary.AvailableCpuPercent))
+	}
+	if summary.StatsBlockedRatio >= clientSaturationStatsBlockedRatio {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("the workers spent %.1f%% of their time blocked recording their datapoints", summary.StatsBlockedRatio*100.0))
+	}
+	if summary.GCPauses["q99"] >= clientSaturationGCPauseP99Millis {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("the client GC pauses p99 is %.1f ms", summary.GCPauses["q99"]))
+	}
+	summary.Saturated = len(summary.Warnings) > 0
+	return summary
+}
+
+func copyFloat64Histogram(h *metrics.Float64Histogram) *metrics.Float64Histogram {
+	return &metrics.Float64Histogram{Counts: append([]uint64{}, h.Counts...), Buckets: h.Buckets}
+}
+
+// subtractFloat64Histogram returns the observations of h that happened after the since snapshot
+func subtractFloat64Histogram(h *metrics.Float64Histogram, since *metrics.Float64Histogram) *metrics.Float64Histogram {
+	diff := copyFloat64Histogram(h)
+	for i := range diff.Counts {
+		if i < len(since.Counts) {
+			diff.Counts[i] -= since.Counts[i]
+		}
+	}
+	return diff
+}
+
+func histogramCount(h *metrics.Float64Histogram) (count uint64) {
+	for _, c := range h.Counts {
+		count += c
+	}
+	return
+}
+
+// histogramQuantile returns the upper bound of the bucket holding the quantile ( between 0 and 1 ), or its lower bound
+// for the unbounded last bucket
+func histogramQuantile(h *metrics.Float64Histogram, quantile float64) float64 {
+	total := histogramCount(h)
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(quantile * float64(total)))
+	if target == 0 {
+		target = 1
+	}
+	seen := uint64(0)
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= target {
+			if math.IsInf(h.Buckets[i+1], 1) {
+				return h.Buckets[i]
+			}
+			return h.Buckets[i+1]
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1]
+}
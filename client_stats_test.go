@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"runtime/metrics"
+	"testing"
+)
+
+func Test_histogramQuantile(t *testing.T) {
+	h := &metrics.Float64Histogram{
+		Counts:  []uint64{0, 90, 9, 1},
+		Buckets: []float64{0, 0.001, 0.002, 0.004, math.Inf(1)},
+	}
+	tests := []struct {
+		quantile float64
+		want     float64
+	}{
+		{0, 0.002},
+		{0.5, 0.002},
+		{0.9, 0.002},
+		{0.99, 0.004},
+		{1, 0.004},
+	}
+	for _, tt := range tests {
+		if got := histogramQuantile(h, tt.quantile); got != tt.want {
+			t.Errorf("histogramQuantile(%v) = %v, want %v", tt.quantile, got, tt.want)
+		}
+	}
+	if got := histogramQuantile(&metrics.Float64Histogram{Counts: []uint64{0}, Buckets: []float64{0, 1}}, 0.5); got != 0 {
+		t.Errorf("histogramQuantile() of an empty histogram = %v, want 0", got)
+	}
+}
+
+func Test_subtractFloat64Histogram(t *testing.T) {
+	since := &metrics.Float64Histogram{Counts: []uint64{1, 2, 3}, Buckets: []float64{0, 1, 2, 3}}
+	h := &metrics.Float64Histogram{Counts: []uint64{1, 5, 10}, Buckets: []float64{0, 1, 2, 3}}
+	diff := subtractFloat64Histogram(h, since)
+	if diff.Counts[0] != 0 || diff.Counts[1] != 3 || diff.Counts[2] != 7 {
+		t.Errorf("subtractFloat64Histogram() = %v, want %v", diff.Counts, []uint64{0, 3, 7})
+	}
+	if h.Counts[1] != 5 {
+		t.Errorf("subtractFloat64Histogram() modified its input")
+	}
+	if histogramCount(diff) != 10 {
+		t.Errorf("histogramCount() = %v, want %v", histogramCount(diff), 10)
+	}
+}
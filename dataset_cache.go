@@ -0,0 +1,354 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// name of the RDB file the database loads, and of the decompressed RDB file in a cache entry
+	datasetFile = "dataset.rdb"
+	// HTTP validators of a dataset cached by URL
+	cachedValidatorsFile    = "validators.json"
+	datasetProgressPeriod   = 5 * time.Second
+	datasetCacheFallbackDir = ".falkordb-benchmark-cache"
+)
+
+// defaultDatasetCacheDir is the datasets directory of the user cache, or a directory of the current one
+func defaultDatasetCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return datasetCacheFallbackDir
+	}
+	return filepath.Join(dir, "falkordb-benchmark", "datasets")
+}
+
+// datasetCacheKey names the cache entry of a dataset URL: its expected checksum when there is one, so that the same
+// content is only downloaded once, and the URL otherwise, in which case the entry is revalidated on every use
+func datasetCacheKey(datasetUrl, expectedSha256 string) string {
+	if expectedSha256 != "" {
+		return "sha256-" + strings.ToLower(expectedSha256)
+	}
+	sum := sha256.Sum256([]byte(datasetUrl))
+	return "url-" + hex.EncodeToString(sum[:])
+}
+
+// datasetValidators are the HTTP validators of a dataset downloaded without a checksum, sent back to the server to
+// check that the cached file did not change
+type datasetValidators struct {
+	ETag         string `json:"ETag,omitempty"`
+	LastModified string `json:"LastModified,omitempty"`
+}
+
+func (v *datasetValidators) empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// cachedDataset returns the decompressed RDB file of a dataset URL, from the cache when it was already downloaded.
+// Cache entries are only created once complete and verified, so an interrupted download is never reused. Entries of
+// a checksum are used as is, while entries of a URL are only used as long as the server reports them unmodified.
+func cachedDataset(datasetUrl, expectedSha256, cacheDir string) (string, error) {
+	entry := filepath.Join(cacheDir, datasetCacheKey(datasetUrl, expectedSha256))
+	rdb := filepath.Join(entry, datasetFile)
+	var validators *datasetValidators
+	if _, err := os.Stat(rdb); err == nil {
+		if expectedSha256 != "" {
+			fmt.Printf("Using the cached dataset %s\n", rdb)
+			return rdb, nil
+		}
+		// an entry without validators can't be revalidated, and is downloaded again
+		validators = readDatasetValidators(entry)
+	}
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		return "", err
+	}
+	// concurrent runs sharing the cache each download into their own archive, and only rename the final RDB file. The
+	// archive keeps the URL file name, whose extension tells how to extract it.
+	download, err := os.CreateTemp(entry, "download-*-"+datasetArchiveName(datasetUrl))
+	if err != nil {
+		return "", err
+	}
+	archive := download.Name()
+	download.Close()
+	defer os.Remove(archive)
+	fresh, notModified, err := fetchDataset(datasetUrl, archive, expectedSha256, validators)
+	if notModified {
+		fmt.Printf("Using the cached dataset %s, not modified since it was downloaded\n", rdb)
+		return rdb, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// the validators of the previous download must not outlive its file
+	if err = os.Remove(filepath.Join(entry, cachedValidatorsFile)); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if !isCompressedDataset(archive) {
+		err = os.Rename(archive, rdb)
+	} else {
+		err = extractDataset(archive, rdb)
+	}
+	if err != nil {
+		return "", err
+	}
+	if expectedSha256 == "" && !fresh.empty() {
+		if err = writeDatasetValidators(entry, fresh); err != nil {
+			return "", err
+		}
+	}
+	return rdb, nil
+}
+
+// readDatasetValidators returns the validators of a cache entry, or nil when it has none
+func readDatasetValidators(entry string) *datasetValidators {
+	data, err := os.ReadFile(filepath.Join(entry, cachedValidatorsFile))
+	if err != nil {
+		return nil
+	}
+	validators := &datasetValidators{}
+	if err = json.Unmarshal(data, validators); err != nil || validators.empty() {
+		return nil
+	}
+	return validators
+}
+
+func writeDatasetValidators(entry string, validators datasetValidators) error {
+	data, err := json.Marshal(validators)
+	if err != nil {
+		return err
+	}
+	return writeVerifiedFile(filepath.Join(entry, cachedValidatorsFile), strings.NewReader(string(data)), "")
+}
+
+func datasetArchiveName(datasetUrl string) string {
+	if u, err := url.Parse(datasetUrl); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		return path.Base(u.Path)
+	}
+	return datasetFile
+}
+
+// DownloadDataset downloads the URL into destination, verifying its checksum when one is expected.
+// The file is written under a temporary name and renamed once complete.
+func DownloadDataset(datasetUrl string, destination string, expectedSha256 string) error {
+	_, _, err := fetchDataset(datasetUrl, destination, expectedSha256, nil)
+	return err
+}
+
+// fetchDataset downloads the URL into destination like DownloadDataset. When validators of a previous download are
+// given, the request is conditional, and nothing is downloaded when the server reports the file as not modified.
+// It returns the validators of the downloaded file.
+func fetchDataset(datasetUrl string, destination string, expectedSha256 string, validators *datasetValidators) (fresh datasetValidators, notModified bool, err error) {
+	request, err := http.NewRequest(http.MethodGet, datasetUrl, nil)
+	if err != nil {
+		return
+	}
+	if validators != nil {
+		fmt.Printf("Revalidating the cached dataset %s\n", datasetUrl)
+		if validators.ETag != "" {
+			request.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			request.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	} else {
+		fmt.Printf("Downloading dataset %s\n", datasetUrl)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	// Check server response
+	if validators != nil && resp.StatusCode == http.StatusNotModified {
+		return fresh, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to download dataset, server returned bad status: %s", resp.Status)
+		return
+	}
+	if validators != nil {
+		fmt.Printf("The cached dataset changed, downloading it again\n")
+	}
+
+	progress := &datasetProgress{action: "Downloaded", total: resp.ContentLength, startT: time.Now(), lastT: time.Now()}
+	if err = writeVerifiedFile(destination, io.TeeReader(resp.Body, progress), expectedSha256); err != nil {
+		return
+	}
+	fmt.Printf("Downloaded dataset, %s in %.1f secs\n", formatBytes(progress.done), time.Since(progress.startT).Seconds())
+	fresh = datasetValidators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	return
+}
+
+// writeVerifiedFile atomically writes the reader content into destination, when its checksum is the expected one
+func writeVerifiedFile(destination string, reader io.Reader, expectedSha256 string) (err error) {
+	out, err := os.CreateTemp(filepath.Dir(destination), ".partial-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+	hash := sha256.New()
+	if _, err = io.Copy(out, io.TeeReader(reader, hash)); err != nil {
+		return err
+	}
+	if err = checkSha256(hash.Sum(nil), expectedSha256); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), destination)
+}
+
+func checkSha256(sum []byte, expectedSha256 string) error {
+	if expectedSha256 == "" {
+		return nil
+	}
+	if actual := hex.EncodeToString(sum); !strings.EqualFold(actual, expectedSha256) {
+		return fmt.Errorf("dataset checksum mismatch, expected sha256 %s but got %s", expectedSha256, actual)
+	}
+	return nil
+}
+
+// verifyDatasetFile checks the checksum of a local dataset file
+func verifyDatasetFile(filename, expectedSha256 string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return err
+	}
+	return checkSha256(hash.Sum(nil), expectedSha256)
+}
+
+func isTarDataset(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".tgz") || strings.Contains(name, ".tar.")
+}
+
+// isCompressedDataset tells whether the file is not an RDB file, but a .gz or .zst file, or a tar archive
+func isCompressedDataset(name string) bool {
+	compression := strings.ToLower(filepath.Ext(name))
+	return isTarDataset(name) || compression == ".gz" || compression == ".zst"
+}
+
+// extractDataset decompresses a .gz or .zst file, or extracts the RDB file of a tar archive, into destination
+func extractDataset(archive, destination string) error {
+	isTar := isTarDataset(archive)
+	compression := strings.ToLower(filepath.Ext(archive))
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	switch compression {
+	case ".gz", ".tgz":
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("unable to decompress %s: %v", archive, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case ".zst":
+		zstdReader, err := zstd.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("unable to decompress %s: %v", archive, err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	}
+	if isTar {
+		if reader, err = tarDataset(tar.NewReader(reader)); err != nil {
+			return fmt.Errorf("unable to extract %s: %v", archive, err)
+		}
+	}
+	fmt.Printf("Decompressing dataset %s\n", archive)
+	if err = writeVerifiedFile(destination, reader, ""); err != nil {
+		return fmt.Errorf("unable to decompress %s: %v", archive, err)
+	}
+	return nil
+}
+
+// tarDataset positions the tar reader on its first .rdb file
+func tarDataset(reader *tar.Reader) (io.Reader, error) {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, errors.New("the archive holds no .rdb file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && strings.HasSuffix(strings.ToLower(header.Name), ".rdb") {
+			return reader, nil
+		}
+	}
+}
+
+// placeDataset copies the RDB file where the database loads it from. The file is never linked, so that nothing
+// writing to the working copy can modify the cached one.
+func placeDataset(rdb, destination string) error {
+	if err := os.Remove(destination); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return CopyFile(rdb, destination)
+}
+
+// datasetProgress prints the progress of a long copy every few seconds
+type datasetProgress struct {
+	action string
+	// -1 when unknown
+	total  int64
+	done   int64
+	startT time.Time
+	lastT  time.Time
+}
+
+func (p *datasetProgress) Write(data []byte) (int, error) {
+	p.done += int64(len(data))
+	if now := time.Now(); now.Sub(p.lastT) >= datasetProgressPeriod {
+		p.lastT = now
+		if p.total > 0 {
+			fmt.Printf("%s %s of %s ( %.0f%% )\n", p.action, formatBytes(p.done), formatBytes(p.total), float64(p.done)/float64(p.total)*100.0)
+		} else {
+			fmt.Printf("%s %s\n", p.action, formatBytes(p.done))
+		}
+	}
+	return len(data), nil
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
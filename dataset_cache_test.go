@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/klauspost/compress/zstd"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_extractDataset(t *testing.T) {
+	content := []byte("REDIS0011 dataset")
+	var gzipped, zstded, tarred, tarGzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write(content)
+	gzipWriter.Close()
+	zstdWriter, _ := zstd.NewWriter(&zstded)
+	zstdWriter.Write(content)
+	zstdWriter.Close()
+	writeTar := func(buffer *bytes.Buffer) {
+		tarWriter := tar.NewWriter(buffer)
+		tarWriter.WriteHeader(&tar.Header{Name: "README", Mode: 0644, Size: 2, Typeflag: tar.TypeReg})
+		tarWriter.Write([]byte("hi"))
+		tarWriter.WriteHeader(&tar.Header{Name: "data/dump.rdb", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tarWriter.Write(content)
+		tarWriter.Close()
+	}
+	writeTar(&tarred)
+	gzipWriter = gzip.NewWriter(&tarGzipped)
+	gzipWriter.Write(tarred.Bytes())
+	gzipWriter.Close()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"dump.rdb.gz", gzipped.Bytes()},
+		{"dump.rdb.zst", zstded.Bytes()},
+		{"dump.tar", tarred.Bytes()},
+		{"dump.tar.gz", tarGzipped.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(archive, tt.archive, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if !isCompressedDataset(archive) {
+				t.Fatalf("isCompressedDataset(%s) = false", tt.name)
+			}
+			destination := filepath.Join(dir, tt.name+".out")
+			if err := extractDataset(archive, destination); err != nil {
+				t.Fatalf("extractDataset() error = %v", err)
+			}
+			if got, _ := os.ReadFile(destination); !bytes.Equal(got, content) {
+				t.Errorf("extractDataset() = %q, want %q", got, content)
+			}
+		})
+	}
+	if isCompressedDataset("dump.rdb") {
+		t.Errorf("isCompressedDataset(dump.rdb) = true")
+	}
+}
+
+func Test_cachedDataset(t *testing.T) {
+	content := []byte("REDIS0011 dataset")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content)
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	if _, err := cachedDataset(server.URL+"/dump.rdb", sha256Hex("other"), cacheDir); err == nil {
+		t.Fatalf("cachedDataset() expected a checksum mismatch")
+	}
+	entry := filepath.Join(cacheDir, datasetCacheKey(server.URL+"/dump.rdb", sha256Hex("other")))
+	if files, _ := os.ReadDir(entry); len(files) != 0 {
+		t.Errorf("cachedDataset() left %d files after a checksum mismatch", len(files))
+	}
+
+	for i := 0; i < 2; i++ {
+		rdb, err := cachedDataset(server.URL+"/dump.rdb", checksum, cacheDir)
+		if err != nil {
+			t.Fatalf("cachedDataset() error = %v", err)
+		}
+		if got, _ := os.ReadFile(rdb); !bytes.Equal(got, content) {
+			t.Errorf("cachedDataset() = %q, want %q", got, content)
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("cachedDataset() sent %d requests, want 2: the mismatch and a single download", got)
+	}
+	if _, err := cachedDataset(server.URL+"/other.rdb", checksum, cacheDir); err != nil || requests.Load() != 2 {
+		t.Errorf("cachedDataset() of another URL with the same checksum downloaded it again, error = %v", err)
+	}
+}
+
+func Test_cachedDataset_revalidation(t *testing.T) {
+	content := "REDIS0011 v1"
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + sha256Hex(content) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	for _, tt := range []struct {
+		content       string
+		wantDownloads int32
+	}{
+		{"REDIS0011 v1", 1},
+		{"REDIS0011 v1", 1},
+		{"REDIS0011 v2", 2},
+	} {
+		content = tt.content
+		rdb, err := cachedDataset(server.URL+"/dump.rdb", "", cacheDir)
+		if err != nil {
+			t.Fatalf("cachedDataset() error = %v", err)
+		}
+		if got, _ := os.ReadFile(rdb); string(got) != tt.content || downloads.Load() != tt.wantDownloads {
+			t.Errorf("cachedDataset() = %q after %d downloads, want %q after %d", got, downloads.Load(), tt.content, tt.wantDownloads)
+		}
+	}
+}
+
+func Test_placeDataset(t *testing.T) {
+	dir := t.TempDir()
+	rdb, destination := filepath.Join(dir, "cached.rdb"), filepath.Join(dir, "dataset.rdb")
+	if err := os.WriteFile(rdb, []byte("cached"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := placeDataset(rdb, destination); err != nil {
+		t.Fatalf("placeDataset() error = %v", err)
+	}
+	if err := os.WriteFile(destination, []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(rdb); string(got) != "cached" {
+		t.Errorf("writing the placed dataset modified the cached file, which is now %q", got)
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func Test_formatBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KiB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.bytes); got != tt.want {
+			t.Errorf("formatBytes(%d) = %v, want %v", tt.bytes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCsvConcurrency = 4
+
+// csvBatch holds the rows of a write query, rendered as a Cypher list of maps
+type csvBatch struct {
+	rows string
+	size int
+	// CSV ids of the nodes of the batch, in the rows order
+	keys []string
+}
+
+// csvLoader loads node and relationship files with batched UNWIND writes, issued concurrently. Relationships match
+// their nodes by internal id, so the internal id of every CSV node id is kept in memory.
+type csvLoader struct {
+	config  *CsvDatasetConfig
+	connect func() *falkordb.Graph
+	// no more batch is issued once the run is stopped
+	stopper *runStopper
+	// label to CSV node id to internal node id
+	nodeIdsMutex sync.Mutex
+	nodeIds      map[string]map[string]int64
+}
+
+// loadCsvDataset loads the nodes files, then the relationships files and finally creates the indices.
+// connect opens a new connection to the benchmark graph, one per concurrent writer. Stopping the run interrupts the load.
+func loadCsvDataset(graph *falkordb.Graph, config *CsvDatasetConfig, connect func() *falkordb.Graph, stopper *runStopper) (*IngestionStats, error) {
+	startT := time.Now()
+	loader := &csvLoader{config: config, connect: connect, stopper: stopper, nodeIds: map[string]map[string]int64{}}
+	var steps []IngestionStepStats
+	for _, nodes := range config.Nodes {
+		step := newIngestionStep(nodesStepName(nodes.Label), nodes.File)
+		ids := map[string]int64{}
+		loader.nodeIds[nodes.Label] = ids
+		err := loader.runBatches(step, createNodesQuery(nodes.Label), func(emit func(csvBatch) bool) error {
+			return loader.nodesBatches(nodes, emit)
+		}, func(batch csvBatch, result *falkordb.QueryResult) error {
+			_, created := resultSetRows(result)
+			loader.nodeIdsMutex.Lock()
+			defer loader.nodeIdsMutex.Unlock()
+			for i, row := range created {
+				if _, exists := ids[batch.keys[i]]; exists {
+					return fmt.Errorf("node id %s is used more than once", batch.keys[i])
+				}
+				ids[batch.keys[i]] = row[0].(int64)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the (:%s) nodes of %s: %v", nodes.Label, nodes.File, err)
+		}
+		steps = append(steps, step.finish())
+	}
+	for _, relationship := range config.Relationships {
+		step := newIngestionStep(relationshipsStepName(relationship.Type), relationship.File)
+		err := loader.runBatches(step, createRelationshipsQuery(relationship.Type), func(emit func(csvBatch) bool) error {
+			return loader.relationshipsBatches(relationship, emit)
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the [:%s] relationships of %s: %v", relationship.Type, relationship.File, err)
+		}
+		steps = append(steps, step.finish())
+	}
+	if len(config.Indices) > 0 {
+		step, err := createIndices(graph, config.Indices)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return newIngestionStats(ingestionSourceCsv, config.BatchSize, config.Concurrency, steps, time.Since(startT)), nil
+}
+
+// runBatches issues the batches emitted by produce on concurrent connections, until produce returns, a batch fails or
+// the run is stopped
+func (l *csvLoader) runBatches(step *ingestionStep, query string, produce func(emit func(csvBatch) bool) error, done func(csvBatch, *falkordb.QueryResult) error) error {
+	batches := make(chan csvBatch, l.config.Concurrency)
+	stop := make(chan struct{})
+	var failure error
+	var failOnce sync.Once
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failure = err
+			close(stop)
+		})
+	}
+	wg := sync.WaitGroup{}
+	for w := 0; w < l.config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			graph := l.connect()
+			defer graph.Conn.Close()
+			for batch := range batches {
+				select {
+				case <-stop:
+					continue
+				case <-l.stopper.Done():
+					continue
+				default:
+				}
+				startT := time.Now()
+				result, err := graph.Query("CYPHER rows="+batch.rows+" "+query, nil, nil)
+				if err == nil && done != nil {
+					err = done(batch, result)
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+				step.record(batch.size, time.Since(startT))
+			}
+		}()
+	}
+	err := produce(func(batch csvBatch) bool {
+		if l.stopper.Stopped() {
+			return false
+		}
+		select {
+		case batches <- batch:
+			return true
+		case <-stop:
+			return false
+		case <-l.stopper.Done():
+			return false
+		}
+	})
+	close(batches)
+	wg.Wait()
+	if failure != nil {
+		return failure
+	}
+	if err == nil && l.stopper.Stopped() {
+		return fmt.Errorf("stopped: %s", l.stopper.Reason())
+	}
+	return err
+}
+
+func (l *csvLoader) nodesBatches(nodes CsvNodesFile, emit func(csvBatch) bool) error {
+	return readCsvBatches(nodes.File, l.config.BatchSize, func(headers []string) (csvRowRenderer, error) {
+		idColumn := slices.Index(headers, nodes.Id)
+		if idColumn < 0 {
+			return nil, fmt.Errorf("id column %s not found, available columns are %v", nodes.Id, headers)
+		}
+		properties := newCsvPropertiesRenderer(headers, nodes.Types)
+		return func(row *strings.Builder, record []string) (string, error) {
+			key := strings.TrimSpace(record[idColumn])
+			if key == "" {
+				return "", fmt.Errorf("empty node id")
+			}
+			return key, properties(row, record)
+		}, nil
+	}, emit)
+}
+
+func (l *csvLoader) relationshipsBatches(relationship CsvRelationshipFile, emit func(csvBatch) bool) error {
+	sourceIds, destinationIds := l.nodeIds[relationship.From], l.nodeIds[relationship.To]
+	return readCsvBatches(relationship.File, l.config.BatchSize, func(headers []string) (csvRowRenderer, error) {
+		fromColumn, toColumn := slices.Index(headers, relationship.FromColumn), slices.Index(headers, relationship.ToColumn)
+		if fromColumn < 0 || toColumn < 0 {
+			return nil, fmt.Errorf("columns %s and %s are required, available columns are %v", relationship.FromColumn, relationship.ToColumn, headers)
+		}
+		properties := newCsvPropertiesRenderer(headers, relationship.Types, fromColumn, toColumn)
+		return func(row *strings.Builder, record []string) (string, error) {
+			src, found := sourceIds[strings.TrimSpace(record[fromColumn])]
+			if !found {
+				return "", fmt.Errorf("unknown (:%s) node id %s", relationship.From, record[fromColumn])
+			}
+			dst, found := destinationIds[strings.TrimSpace(record[toColumn])]
+			if !found {
+				return "", fmt.Errorf("unknown (:%s) node id %s", relationship.To, record[toColumn])
+			}
+			fmt.Fprintf(row, "{src: %d, dst: %d, properties: ", src, dst)
+			if err := properties(row, record); err != nil {
+				return "", err
+			}
+			row.WriteByte('}')
+			return "", nil
+		}, nil
+	}, emit)
+}
+
+// csvRowRenderer writes a record as a Cypher value, and returns the CSV node id of the record, if any
+type csvRowRenderer func(row *strings.Builder, record []string) (string, error)
+
+// newCsvPropertiesRenderer writes the non empty columns of a record as a Cypher map of typed values, except the
+// skipped columns
+func newCsvPropertiesRenderer(headers []string, types map[string]string, skipped ...int) func(row *strings.Builder, record []string) error {
+	columnTypes := make([]string, len(headers))
+	for i, header := range headers {
+		columnTypes[i] = termTypeString
+		if columnType, found := types[header]; found {
+			columnTypes[i] = columnType
+		}
+	}
+	return func(row *strings.Builder, record []string) error {
+		row.WriteByte('{')
+		first := true
+		for i, term := range record {
+			if term == "" || slices.Contains(skipped, i) {
+				continue
+			}
+			value, err := cypherParamValue(term, columnTypes[i])
+			if err != nil {
+				return fmt.Errorf("column %s: %v", headers[i], err)
+			}
+			if !first {
+				row.WriteString(", ")
+			}
+			first = false
+			row.WriteString(cypherName(headers[i]))
+			row.WriteString(": ")
+			row.WriteString(value)
+		}
+		row.WriteByte('}')
+		return nil
+	}
+}
+
+// readCsvBatches streams a CSV file with a header, and emits its records rendered by batches of batchSize rows
+func readCsvBatches(filename string, batchSize int, newRenderer func(headers []string) (csvRowRenderer, error), emit func(csvBatch) bool) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+	headers, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read the header of %s: %v", filename, err)
+	}
+	headers = slices.Clone(headers)
+	for i := range headers {
+		headers[i] = strings.TrimSpace(headers[i])
+	}
+	render, err := newRenderer(headers)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	var rows strings.Builder
+	batch := csvBatch{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", filename, err)
+		}
+		if batch.size == 0 {
+			rows.WriteByte('[')
+		} else {
+			rows.WriteByte(',')
+		}
+		key, err := render(&rows, record)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return fmt.Errorf("%s line %d: %v", filename, line, err)
+		}
+		if key != "" {
+			batch.keys = append(batch.keys, key)
+		}
+		if batch.size++; batch.size == batchSize {
+			rows.WriteByte(']')
+			batch.rows = rows.String()
+			if !emit(batch) {
+				return nil
+			}
+			rows.Reset()
+			batch = csvBatch{}
+		}
+	}
+	if batch.size > 0 {
+		rows.WriteByte(']')
+		batch.rows = rows.String()
+		emit(batch)
+	}
+	return nil
+}
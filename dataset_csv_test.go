@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/FalkorDB/falkordb-go"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func Test_readCsvBatches(t *testing.T) {
+	filename := writeTermsFile(t, "users.csv", "id, name ,a`ge\n1,Ann,30\n2,\"Bob \"\"B\"\"\",\n3,Cid,x\n")
+	loader := &csvLoader{config: &CsvDatasetConfig{BatchSize: 2}}
+	var batches []csvBatch
+	err := loader.nodesBatches(CsvNodesFile{Label: "User", File: filename, Id: "id", Types: map[string]string{"id": termTypeInt}}, func(batch csvBatch) bool {
+		batches = append(batches, batch)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("nodesBatches() error = %v", err)
+	}
+	want := []csvBatch{
+		{rows: "[{`id`: 1, `name`: \"Ann\", `a``ge`: \"30\"},{`id`: 2, `name`: \"Bob \\\"B\\\"\"}]", size: 2, keys: []string{"1", "2"}},
+		{rows: "[{`id`: 3, `name`: \"Cid\", `a``ge`: \"x\"}]", size: 1, keys: []string{"3"}},
+	}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("nodesBatches() = %v, want %v", batches, want)
+	}
+	err = loader.nodesBatches(CsvNodesFile{Label: "User", File: filename, Id: "id", Types: map[string]string{"a`ge": termTypeInt}}, func(batch csvBatch) bool { return true })
+	if err == nil {
+		t.Errorf("nodesBatches() expected an error on an int column holding 'x'")
+	}
+	err = loader.nodesBatches(CsvNodesFile{Label: "User", File: filename, Id: "user_id"}, func(batch csvBatch) bool { return true })
+	if err == nil {
+		t.Errorf("nodesBatches() expected an error on a missing id column")
+	}
+}
+
+func Test_relationshipsBatches(t *testing.T) {
+	filename := writeTermsFile(t, "follows.csv", "src,dst,since\n1,2,2020\n2,1,\n")
+	loader := &csvLoader{config: &CsvDatasetConfig{BatchSize: 10}, nodeIds: map[string]map[string]int64{"User": {"1": 10, "2": 20}}}
+	relationship := CsvRelationshipFile{Type: "FOLLOWS", File: filename, From: "User", To: "User", FromColumn: "src", ToColumn: "dst", Types: map[string]string{"since": termTypeInt}}
+	var batches []csvBatch
+	if err := loader.relationshipsBatches(relationship, func(batch csvBatch) bool {
+		batches = append(batches, batch)
+		return true
+	}); err != nil {
+		t.Fatalf("relationshipsBatches() error = %v", err)
+	}
+	want := []csvBatch{{rows: "[{src: 10, dst: 20, properties: {`since`: 2020}},{src: 20, dst: 10, properties: {}}]", size: 2}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("relationshipsBatches() = %v, want %v", batches, want)
+	}
+	delete(loader.nodeIds["User"], "2")
+	if err := loader.relationshipsBatches(relationship, func(batch csvBatch) bool { return true }); err == nil {
+		t.Errorf("relationshipsBatches() expected an error on an unknown node id")
+	}
+}
+
+func Test_runBatches(t *testing.T) {
+	listener := noopGraphServer(t)
+	defer listener.Close()
+	loader := &csvLoader{config: &CsvDatasetConfig{BatchSize: 1, Concurrency: 3}, stopper: newRunStopper(), connect: func() *falkordb.Graph {
+		graph, _ := getStandaloneConn("csv", listener.Addr().String(), "", "", 5)
+		return graph
+	}}
+	step := newIngestionStep("[:FOLLOWS]", "")
+	err := loader.runBatches(step, createRelationshipsQuery("FOLLOWS"), func(emit func(csvBatch) bool) error {
+		for i := 0; i < 50; i++ {
+			if !emit(csvBatch{rows: "[{src: 0, dst: 1, properties: {}}]", size: 1}) {
+				break
+			}
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("runBatches() error = %v", err)
+	}
+	if stats := step.finish(); stats.Entities != 50 || stats.Batches != 50 {
+		t.Errorf("runBatches() recorded %d entities in %d batches, want 50 in 50", stats.Entities, stats.Batches)
+	}
+
+	loader.stopper.Stop(stopReasonInterrupted)
+	emitted := 0
+	err = loader.runBatches(newIngestionStep("[:FOLLOWS]", ""), createRelationshipsQuery("FOLLOWS"), func(emit func(csvBatch) bool) error {
+		for emitted < 50 && emit(csvBatch{rows: "[{src: 0, dst: 1, properties: {}}]", size: 1}) {
+			emitted++
+		}
+		return nil
+	}, nil)
+	if err == nil || emitted != 0 {
+		t.Errorf("runBatches() emitted %d batches once the run was stopped, error = %v", emitted, err)
+	}
+}
+
+func Test_DatasetConfig_UnmarshalYAML(t *testing.T) {
+	var config struct {
+		Dataset *DatasetConfig `yaml:"dataset"`
+	}
+	if err := yaml.Unmarshal([]byte("dataset: https://example.com/dataset.rdb\n"), &config); err != nil || config.Dataset.Path != "https://example.com/dataset.rdb" {
+		t.Errorf("UnmarshalYAML() of a string = %+v, %v", config.Dataset, err)
+	}
+	if err := yaml.Unmarshal([]byte("dataset:\n  csv:\n    nodes:\n      - label: User\n        file: users.csv\n"), &config); err != nil || config.Dataset.Csv == nil || config.Dataset.Csv.Nodes[0].File != "users.csv" {
+		t.Errorf("UnmarshalYAML() of a mapping = %+v, %v", config.Dataset, err)
+	}
+}
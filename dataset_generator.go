@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGenerateBatchSize = 1000
+	defaultPowerLawExponent  = 2.5
+	defaultPropertyMax       = 1000000
+	defaultPropertyLength    = 8
+
+	degreeUniform    = "uniform"
+	degreePowerLaw   = "power_law"
+	degreeErdosRenyi = "erdos_renyi"
+
+	propertyTypeId     = "id"
+	propertyTypeInt    = "int"
+	propertyTypeFloat  = "float"
+	propertyTypeString = "string"
+	propertyTypeBool   = "bool"
+
+	propertyLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// graphGenerator builds a synthetic graph with batched UNWIND writes. Relationships match their nodes by internal id,
+// so the ids of the created nodes are kept, 8 bytes per node.
+type graphGenerator struct {
+	graph     *falkordb.Graph
+	batchSize int
+	random    *rand.Rand
+	nodeIds   map[string][]int64
+	// no more batch is issued once the run is stopped
+	stopper *runStopper
+}
+
+// generateGraph creates the nodes, then the relationships and finally the indices of the model. Stopping the run
+// interrupts the generation.
+func generateGraph(graph *falkordb.Graph, generate *GenerateConfig, seed int64, stopper *runStopper) (*IngestionStats, error) {
+	startT := time.Now()
+	generator := &graphGenerator{
+		graph:     graph,
+		batchSize: generate.BatchSize,
+		random:    rand.New(rand.NewSource(seed)),
+		nodeIds:   map[string][]int64{},
+		stopper:   stopper,
+	}
+	var steps []IngestionStepStats
+	for _, nodes := range generate.Nodes {
+		step := newIngestionStep(nodesStepName(nodes.Label), "")
+		if err := generator.createNodes(nodes, step); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step.finish())
+	}
+	for _, relationship := range generate.Relationships {
+		step := newIngestionStep(relationshipsStepName(relationship.Type), "")
+		if err := generator.createRelationships(relationship, step); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step.finish())
+	}
+	if len(generate.Indices) > 0 {
+		step, err := createIndices(graph, generate.Indices)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return newIngestionStats(ingestionSourceGenerate, generate.BatchSize, 1, steps, time.Since(startT)), nil
+}
+
+// The rows of a batch are rendered as a Cypher list of maps, like the CSV rows, so that any property name is quoted
+func (g *graphGenerator) createNodes(nodes GenerateNodes, step *ingestionStep) error {
+	query := createNodesQuery(nodes.Label)
+	ids := make([]int64, 0, nodes.Count)
+	rows := &strings.Builder{}
+	batchSize := 0
+	for i := 0; i < nodes.Count; i++ {
+		rows.WriteByte(',')
+		g.renderProperties(rows, nodes.Properties, i)
+		batchSize++
+		if batchSize < g.batchSize && i < nodes.Count-1 {
+			continue
+		}
+		if g.stopper.Stopped() {
+			return fmt.Errorf("stopped: %s", g.stopper.Reason())
+		}
+		startT := time.Now()
+		result, err := g.graph.Query(batchQuery(rows, query), nil, nil)
+		if err != nil {
+			return fmt.Errorf("unable to create the (:%s) nodes: %v", nodes.Label, err)
+		}
+		step.record(batchSize, time.Since(startT))
+		_, created := resultSetRows(result)
+		for _, row := range created {
+			ids = append(ids, row[0].(int64))
+		}
+		rows.Reset()
+		batchSize = 0
+	}
+	g.nodeIds[nodes.Label] = ids
+	return nil
+}
+
+func (g *graphGenerator) createRelationships(relationship GenerateRelationship, step *ingestionStep) (err error) {
+	query := createRelationshipsQuery(relationship.Type)
+	sourceIds, destinationIds := g.nodeIds[relationship.From], g.nodeIds[relationship.To]
+	rows := &strings.Builder{}
+	batchSize, created := 0, 0
+	flush := func() error {
+		if g.stopper.Stopped() {
+			return fmt.Errorf("stopped: %s", g.stopper.Reason())
+		}
+		startT := time.Now()
+		_, err := g.graph.Query(batchQuery(rows, query), nil, nil)
+		if err != nil {
+			return fmt.Errorf("unable to create the [:%s] relationships: %v", relationship.Type, err)
+		}
+		step.record(batchSize, time.Since(startT))
+		rows.Reset()
+		batchSize = 0
+		return nil
+	}
+	relationshipEndpoints(relationship, len(sourceIds), len(destinationIds), relationship.From == relationship.To, g.random, func(src, dst int) bool {
+		fmt.Fprintf(rows, ",{src: %d, dst: %d, properties: ", sourceIds[src], destinationIds[dst])
+		g.renderProperties(rows, relationship.Properties, created)
+		rows.WriteByte('}')
+		batchSize++
+		created++
+		if batchSize == g.batchSize {
+			err = flush()
+		}
+		return err == nil
+	})
+	if err == nil && batchSize > 0 {
+		err = flush()
+	}
+	return
+}
+
+// batchQuery passes the rows, each of them prefixed by a comma, as the rows param of the query
+func batchQuery(rows *strings.Builder, query string) string {
+	return "CYPHER rows=[" + rows.String()[1:] + "] " + query
+}
+
+// renderProperties writes the properties of the entity at position pos as a Cypher map
+func (g *graphGenerator) renderProperties(row *strings.Builder, properties []GenerateProperty, pos int) {
+	row.WriteByte('{')
+	for i, property := range properties {
+		if i > 0 {
+			row.WriteString(", ")
+		}
+		row.WriteString(cypherName(property.Name))
+		row.WriteString(": ")
+		row.WriteString(cypherLiteral(propertyValue(property, pos, g.random)))
+	}
+	row.WriteByte('}')
+}
+
+// cypherLiteral writes a generated property value
+func cypherLiteral(value interface{}) string {
+	switch value := value.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return cypherFloat(value)
+	case string:
+		return cypherString(value)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return "null"
+}
+
+// propertyValue returns a value of the property of the entity at position pos
+func propertyValue(property GenerateProperty, pos int, random *rand.Rand) interface{} {
+	switch property.Type {
+	case propertyTypeInt:
+		min, max := int64(property.Min), int64(property.Max)
+		return min + random.Int63n(max-min+1)
+	case propertyTypeFloat:
+		return property.Min + random.Float64()*(property.Max-property.Min)
+	case propertyTypeString:
+		if len(property.Values) > 0 {
+			return property.Values[random.Intn(len(property.Values))]
+		}
+		value := make([]byte, property.Length)
+		for i := range value {
+			value[i] = propertyLetters[random.Intn(len(propertyLetters))]
+		}
+		return string(value)
+	case propertyTypeBool:
+		return random.Intn(2) == 1
+	}
+	return int64(pos)
+}
+
+// relationshipEndpoints draws the relationships of the model, as positions of their source and destination nodes,
+// until emit returns false. Nodes are never connected to themselves.
+func relationshipEndpoints(relationship GenerateRelationship, sources, destinations int, sameLabel bool, random *rand.Rand, emit func(src, dst int) bool) {
+	candidates := destinations
+	if sameLabel {
+		candidates--
+	}
+	if candidates <= 0 {
+		return
+	}
+	destination := func(src, pos int) int {
+		if sameLabel && pos >= src {
+			return pos + 1
+		}
+		return pos
+	}
+	if relationship.Distribution == degreeErdosRenyi {
+		probability := relationship.Probability
+		if probability == 0 {
+			probability = math.Min(relationship.Degree/float64(candidates), 1)
+		}
+		// geometric skips over the pairs of nodes, instead of a coin toss per pair
+		pairs := sources * candidates
+		for pair := -1; ; {
+			pair++
+			if probability < 1 {
+				skip := math.Log(1-random.Float64()) / math.Log(1-probability)
+				if skip >= float64(pairs-pair) {
+					return
+				}
+				pair += int(skip)
+			}
+			if pair >= pairs {
+				return
+			}
+			src := pair / candidates
+			if !emit(src, destination(src, pair%candidates)) {
+				return
+			}
+		}
+	}
+	for src := 0; src < sources; src++ {
+		degree := min(outDegree(relationship, random), candidates)
+		for i := 0; i < degree; i++ {
+			if !emit(src, destination(src, random.Intn(candidates))) {
+				return
+			}
+		}
+	}
+}
+
+// outDegree draws the number of relationships of a source node, averaging the relationship degree
+func outDegree(relationship GenerateRelationship, random *rand.Rand) int {
+	if relationship.Distribution == degreePowerLaw {
+		// pareto distributed, with the minimum degree giving the requested mean
+		alpha := relationship.Exponent - 1
+		minDegree := relationship.Degree * (alpha - 1) / alpha
+		return int(math.Min(math.Round(minDegree*math.Pow(1-random.Float64(), -1/alpha)), math.MaxInt32))
+	}
+	// the fractional part of the degree is the probability of one more relationship
+	degree := int(relationship.Degree)
+	if random.Float64() < relationship.Degree-float64(degree) {
+		degree++
+	}
+	return degree
+}
+
+func nodesStepName(label string) string {
+	return fmt.Sprintf("(:%s)", label)
+}
+
+func relationshipsStepName(relationshipType string) string {
+	return fmt.Sprintf("[:%s]", relationshipType)
+}
+
+// createNodesQuery creates a node per row, returning their ids in the rows order
+func createNodesQuery(label string) string {
+	return fmt.Sprintf("UNWIND $rows AS row CREATE (n:%s) SET n = row RETURN ID(n)", cypherName(label))
+}
+
+// createRelationshipsQuery creates a relationship per row, between nodes matched by their ids
+func createRelationshipsQuery(relationshipType string) string {
+	return fmt.Sprintf("UNWIND $rows AS row MATCH (a), (b) WHERE ID(a) = row.src AND ID(b) = row.dst CREATE (a)-[r:%s]->(b) SET r = row.properties", cypherName(relationshipType))
+}
+
+// createIndices creates the indices, and waits for them to be populated
+func createIndices(graph *falkordb.Graph, indices []GenerateIndex) (IngestionStepStats, error) {
+	step := newIngestionStep("indices", "")
+	for _, index := range indices {
+		query := indexQuery(index)
+		startT := time.Now()
+		if _, err := graph.Query(query, nil, nil); err != nil {
+			return IngestionStepStats{}, fmt.Errorf("unable to create the index with '%s': %v", query, err)
+		}
+		step.record(1, time.Since(startT))
+	}
+	// indices are populated asynchronously
+	for {
+		result, err := graph.ROQuery("CALL db.indexes() YIELD status WHERE status <> 'OPERATIONAL' RETURN count(status)", nil, nil)
+		if err != nil {
+			fmt.Printf("Unable to check the indices status, not waiting for them to be populated. Error: %v\n", err)
+			break
+		}
+		if _, rows := resultSetRows(result); len(rows) == 0 || rows[0][0] == int64(0) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return step.finish(), nil
+}
+
+func indexQuery(index GenerateIndex) string {
+	pattern := fmt.Sprintf("(e:%s)", cypherName(index.Label))
+	if index.Type != "" {
+		pattern = fmt.Sprintf("()-[e:%s]-()", cypherName(index.Type))
+	}
+	properties := make([]string, len(index.Properties))
+	for i, property := range index.Properties {
+		properties[i] = "e." + cypherName(property)
+	}
+	return fmt.Sprintf("CREATE INDEX FOR %s ON (%s)", pattern, strings.Join(properties, ", "))
+}
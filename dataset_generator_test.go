@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func Test_relationshipEndpoints(t *testing.T) {
+	tests := []struct {
+		name         string
+		relationship GenerateRelationship
+		sources      int
+		destinations int
+		sameLabel    bool
+		wantMean     float64
+	}{
+		{"uniform", GenerateRelationship{Distribution: degreeUniform, Degree: 4}, 1000, 1000, true, 4},
+		{"uniform-fractional", GenerateRelationship{Distribution: degreeUniform, Degree: 2.5}, 1000, 50, false, 2.5},
+		{"power-law", GenerateRelationship{Distribution: degreePowerLaw, Degree: 5, Exponent: 3}, 20000, 100000, true, 5},
+		{"erdos-renyi-probability", GenerateRelationship{Distribution: degreeErdosRenyi, Probability: 0.01}, 1000, 1000, true, 9.99},
+		{"erdos-renyi-degree", GenerateRelationship{Distribution: degreeErdosRenyi, Degree: 3}, 1000, 300, false, 3},
+		{"erdos-renyi-complete", GenerateRelationship{Distribution: degreeErdosRenyi, Probability: 1}, 10, 10, true, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			degrees := make([]int, tt.sources)
+			total := 0
+			relationshipEndpoints(tt.relationship, tt.sources, tt.destinations, tt.sameLabel, rand.New(rand.NewSource(12345)), func(src, dst int) bool {
+				if src < 0 || src >= tt.sources || dst < 0 || dst >= tt.destinations {
+					t.Fatalf("relationshipEndpoints() emitted (%d, %d) out of bounds", src, dst)
+				}
+				if tt.sameLabel && src == dst {
+					t.Fatalf("relationshipEndpoints() emitted a self loop on %d", src)
+				}
+				degrees[src]++
+				total++
+				return true
+			})
+			mean := float64(total) / float64(tt.sources)
+			if math.Abs(mean-tt.wantMean) > 0.1*tt.wantMean {
+				t.Errorf("relationshipEndpoints() mean degree = %v, want %v", mean, tt.wantMean)
+			}
+			maxDegree := 0
+			for _, degree := range degrees {
+				maxDegree = max(maxDegree, degree)
+			}
+			if tt.relationship.Distribution == degreePowerLaw && maxDegree < 20*int(tt.wantMean) {
+				t.Errorf("relationshipEndpoints() max degree = %v, expected a heavy tail", maxDegree)
+			}
+			if tt.relationship.Distribution == degreeUniform && maxDegree > int(tt.wantMean)+1 {
+				t.Errorf("relationshipEndpoints() max degree = %v, want at most %v", maxDegree, int(tt.wantMean)+1)
+			}
+		})
+	}
+}
+
+func Test_relationshipEndpoints_stop(t *testing.T) {
+	emitted := 0
+	relationshipEndpoints(GenerateRelationship{Distribution: degreeUniform, Degree: 10}, 100, 100, false, rand.New(rand.NewSource(1)), func(src, dst int) bool {
+		emitted++
+		return emitted < 15
+	})
+	if emitted != 15 {
+		t.Errorf("relationshipEndpoints() emitted %d relationships after being stopped at 15", emitted)
+	}
+}
+
+func Test_propertyValue(t *testing.T) {
+	random := rand.New(rand.NewSource(12345))
+	if got := propertyValue(GenerateProperty{Type: propertyTypeId}, 42, random); got != int64(42) {
+		t.Errorf("propertyValue() id = %v, want 42", got)
+	}
+	for i := 0; i < 1000; i++ {
+		if got := propertyValue(GenerateProperty{Type: propertyTypeInt, Min: -2, Max: 2}, i, random).(int64); got < -2 || got > 2 {
+			t.Fatalf("propertyValue() int = %v, out of [-2, 2]", got)
+		}
+		if got := propertyValue(GenerateProperty{Type: propertyTypeFloat, Min: 0.5, Max: 1}, i, random).(float64); got < 0.5 || got >= 1 {
+			t.Fatalf("propertyValue() float = %v, out of [0.5, 1)", got)
+		}
+	}
+	if got := propertyValue(GenerateProperty{Type: propertyTypeString, Length: 12}, 0, random).(string); len(got) != 12 {
+		t.Errorf("propertyValue() string = %v, want 12 letters", got)
+	}
+	if got := propertyValue(GenerateProperty{Type: propertyTypeString, Values: []string{"a"}}, 0, random); got != "a" {
+		t.Errorf("propertyValue() string of values = %v, want a", got)
+	}
+	if _, ok := propertyValue(GenerateProperty{Type: propertyTypeBool}, 0, random).(bool); !ok {
+		t.Errorf("propertyValue() bool is not a bool")
+	}
+}
+
+func Test_indexQuery(t *testing.T) {
+	tests := []struct {
+		index GenerateIndex
+		want  string
+	}{
+		{GenerateIndex{Label: "User", Properties: []string{"id"}}, "CREATE INDEX FOR (e:`User`) ON (e.`id`)"},
+		{GenerateIndex{Label: "Us`er", Properties: []string{"first name"}}, "CREATE INDEX FOR (e:`Us``er`) ON (e.`first name`)"},
+		{GenerateIndex{Type: "FOLLOWS", Properties: []string{"since", "weight"}}, "CREATE INDEX FOR ()-[e:`FOLLOWS`]-() ON (e.`since`, e.`weight`)"},
+	}
+	for _, tt := range tests {
+		if got := indexQuery(tt.index); got != tt.want {
+			t.Errorf("indexQuery() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func Test_generateGraph_stopped(t *testing.T) {
+	stopper := newRunStopper()
+	stopper.Stop(stopReasonInterrupted)
+	generate := &GenerateConfig{BatchSize: 10, Nodes: []GenerateNodes{{Label: "User", Count: 100}}}
+	// no query is issued once the run is stopped, so no graph is needed
+	if _, err := generateGraph(nil, generate, 12345, stopper); err == nil {
+		t.Errorf("generateGraph() expected an error once the run is stopped")
+	}
+}
+
+func Test_renderProperties(t *testing.T) {
+	generator := &graphGenerator{random: rand.New(rand.NewSource(12345))}
+	properties := []GenerateProperty{
+		{Name: "first name", Type: propertyTypeString, Values: []string{`Ann "A"`}},
+		{Name: "a-b", Type: propertyTypeId},
+		{Name: "score", Type: propertyTypeFloat, Min: 2, Max: 2},
+		{Name: "vip", Type: propertyTypeInt, Min: 1, Max: 1},
+	}
+	row := &strings.Builder{}
+	generator.renderProperties(row, properties, 7)
+	if want := "{`first name`: \"Ann \\\"A\\\"\", `a-b`: 7, `score`: 2.0, `vip`: 1}"; row.String() != want {
+		t.Errorf("renderProperties() = %v, want %v", row.String(), want)
+	}
+	row.Reset()
+	row.WriteString(",{},{}")
+	if got := batchQuery(row, "RETURN 1"); got != "CYPHER rows=[{},{}] RETURN 1" {
+		t.Errorf("batchQuery() = %v", got)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// datasetReferencePattern matches a registry dataset pinned to a version, such as pokec-small@v2
+var datasetReferencePattern = regexp.MustCompile(`^[\w.-]+@[\w.-]+$`)
+
+// DatasetRegistry maps dataset names, then versions, to their files. It is read from a YAML or JSON file.
+type DatasetRegistry struct {
+	Datasets map[string]map[string]RegistryDataset `yaml:"datasets"`
+}
+
+type RegistryDataset struct {
+	// Local path, relative to the registry file, or http(s) URL of an RDB file
+	Path   string `yaml:"path"`
+	Sha256 string `yaml:"sha256,omitempty"`
+	// Expected counts, verified once the dataset is loaded
+	Nodes *int64 `yaml:"nodes,omitempty"`
+	Edges *int64 `yaml:"edges,omitempty"`
+	// Recommended dataset_load_timeout_secs
+	LoadTimeoutSecs int `yaml:"load_timeout_secs,omitempty"`
+}
+
+// DatasetIdentity identifies the data a benchmark ran on, so that only results of the same data are compared
+type DatasetIdentity struct {
+	// Registry name and version, empty for datasets outside of the registry
+	Name    string `json:"Name,omitempty"`
+	Version string `json:"Version,omitempty"`
+	Path    string `json:"Path"`
+	Sha256  string `json:"Sha256,omitempty"`
+	// Counted once the dataset was loaded, nil for the datasets that are not counted
+	Nodes *int64 `json:"Nodes,omitempty"`
+	Edges *int64 `json:"Edges,omitempty"`
+}
+
+func (d *DatasetIdentity) String() string {
+	if d.Name != "" {
+		return d.Name + "@" + d.Version
+	}
+	return d.Path
+}
+
+// sameDataset tells whether both identities are the same data, and whether that can be known at all: registry names
+// and versions are compared first, checksums otherwise
+func sameDataset(a, b *DatasetIdentity) (same bool, known bool) {
+	switch {
+	case a == nil || b == nil:
+		return false, false
+	case a.Name != "" && b.Name != "":
+		return a.Name == b.Name && a.Version == b.Version, true
+	case a.Sha256 != "" && b.Sha256 != "":
+		return strings.EqualFold(a.Sha256, b.Sha256), true
+	}
+	return false, false
+}
+
+// registryDatasetReference tells whether the plain string of a dataset is a registry name@version rather than a path:
+// it must look like one, and be neither a URL nor an existing file
+func registryDatasetReference(dataset *DatasetConfig) bool {
+	if dataset.Name != "" || dataset.Csv != nil || !datasetReferencePattern.MatchString(dataset.Path) || IsURL(dataset.Path) {
+		return false
+	}
+	_, err := os.Stat(dataset.Path)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+func loadDatasetRegistry(filename string) (*DatasetRegistry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("dataset registry does not exist: %v", err)
+	}
+	registry := &DatasetRegistry{}
+	// YAML is a superset of JSON
+	if err = yaml.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("could not parse the dataset registry %s: %v", filename, err)
+	}
+	for name, versions := range registry.Datasets {
+		for version, dataset := range versions {
+			if dataset.Path == "" {
+				return nil, fmt.Errorf("dataset registry entry %s@%s requires a path", name, version)
+			}
+			if dataset.Sha256 != "" && !sha256Pattern.MatchString(dataset.Sha256) {
+				return nil, fmt.Errorf("dataset registry entry %s@%s sha256 must be 64 hexadecimal characters", name, version)
+			}
+			if !IsURL(dataset.Path) {
+				dataset.Path = relativeToYaml(filename, dataset.Path)
+				versions[version] = dataset
+			}
+		}
+	}
+	return registry, nil
+}
+
+// lookup returns the entry of a name@version reference. The version can be omitted when the dataset has a single one.
+func (r *DatasetRegistry) lookup(reference string) (name string, version string, dataset RegistryDataset, err error) {
+	name, version, _ = strings.Cut(reference, "@")
+	versions, found := r.Datasets[name]
+	if !found {
+		return "", "", dataset, fmt.Errorf("dataset %s is not in the registry", name)
+	}
+	available := make([]string, 0, len(versions))
+	for candidate := range versions {
+		available = append(available, candidate)
+	}
+	slices.Sort(available)
+	if version == "" {
+		if len(versions) != 1 {
+			return "", "", dataset, fmt.Errorf("dataset %s has several versions, pin one of %v as %s@<version>", name, available, name)
+		}
+		version = available[0]
+	}
+	if dataset, found = versions[version]; !found {
+		return "", "", dataset, fmt.Errorf("dataset %s has no version %s, available versions are %v", name, version, available)
+	}
+	return name, version, dataset, nil
+}
+
+// resolveRegistryDataset turns a dataset name into the path and checksum of its registry entry. It returns the
+// recommended load timeout of the entry, 0 when it has none.
+func resolveRegistryDataset(dataset *DatasetConfig, registry *DatasetRegistry) (loadTimeoutSecs int, err error) {
+	if dataset.Path != "" || dataset.Csv != nil {
+		return 0, errors.New("a registry dataset name can't have a path or a csv section")
+	}
+	if dataset.Nodes != nil || dataset.Edges != nil {
+		return 0, errors.New("a registry dataset name can't have nodes or edges, the registry ones are expected")
+	}
+	reference := dataset.Name
+	if dataset.Version != "" {
+		if strings.Contains(reference, "@") {
+			return 0, fmt.Errorf("dataset %s has both a name@version and a version", reference)
+		}
+		reference += "@" + dataset.Version
+	}
+	name, version, entry, err := registry.lookup(reference)
+	if err != nil {
+		return 0, err
+	}
+	if dataset.Sha256 != "" && !strings.EqualFold(dataset.Sha256, entry.Sha256) {
+		return 0, fmt.Errorf("dataset %s@%s sha256 is %s in the registry, not %s", name, version, entry.Sha256, dataset.Sha256)
+	}
+	dataset.Path = entry.Path
+	dataset.Sha256 = entry.Sha256
+	dataset.Nodes, dataset.Edges = entry.Nodes, entry.Edges
+	dataset.Identity = &DatasetIdentity{Name: name, Version: version, Path: entry.Path, Sha256: entry.Sha256}
+	return entry.LoadTimeoutSecs, nil
+}
+
+// countedDataset tells whether the nodes and edges of the dataset are counted once it is loaded. Counting scans the
+// graph, so only the registry datasets and the ones with expected counts are counted.
+func countedDataset(dataset *DatasetConfig) bool {
+	return dataset.Identity != nil && (dataset.Identity.Name != "" || dataset.Nodes != nil || dataset.Edges != nil)
+}
+
+// verifyDatasetCounts counts the nodes and edges of the loaded graph into the identity, and checks them against the
+// expected ones
+func verifyDatasetCounts(graph *falkordb.Graph, dataset *DatasetConfig) error {
+	nodes, err := countEntities(graph, "MATCH (n) RETURN count(n)")
+	if err != nil {
+		return err
+	}
+	edges, err := countEntities(graph, "MATCH ()-[e]->() RETURN count(e)")
+	if err != nil {
+		return err
+	}
+	dataset.Identity.Nodes, dataset.Identity.Edges = &nodes, &edges
+	if dataset.Nodes != nil && *dataset.Nodes != nodes {
+		return fmt.Errorf("dataset %s has %d nodes, %d are expected", dataset.Identity, nodes, *dataset.Nodes)
+	}
+	if dataset.Edges != nil && *dataset.Edges != edges {
+		return fmt.Errorf("dataset %s has %d edges, %d are expected", dataset.Identity, edges, *dataset.Edges)
+	}
+	return nil
+}
+
+func countEntities(graph *falkordb.Graph, query string) (int64, error) {
+	result, err := graph.ROQuery(query, nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to run '%s': %v", query, err)
+	}
+	_, rows := resultSetRows(result)
+	if len(rows) != 1 {
+		return 0, fmt.Errorf("'%s' returned %d rows", query, len(rows))
+	}
+	count, _ := rows[0][0].(int64)
+	return count, nil
+}
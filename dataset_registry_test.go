@@ -0,0 +1,152 @@
+package main
+
+import (
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDatasetRegistry = `
+datasets:
+  pokec-small:
+    v1:
+      path: pokec-small-v1.rdb
+      nodes: 10000
+    v2:
+      path: https://example.com/pokec-small-v2.rdb.zst
+      sha256: 0a5c6f1d0d5c7e9ab1c3b6d2d1e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2
+      nodes: 10000
+      edges: 121716
+      load_timeout_secs: 600
+  movies:
+    "2024.1":
+      path: /data/movies.rdb
+`
+
+func Test_DatasetRegistry_lookup(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "registry.yml")
+	if err := os.WriteFile(filename, []byte(testDatasetRegistry), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := loadDatasetRegistry(filename)
+	if err != nil {
+		t.Fatalf("loadDatasetRegistry() error = %v", err)
+	}
+	tests := []struct {
+		reference   string
+		wantVersion string
+		wantPath    string
+		wantErr     bool
+	}{
+		{"pokec-small@v1", "v1", filepath.Join(dir, "pokec-small-v1.rdb"), false},
+		{"pokec-small@v2", "v2", "https://example.com/pokec-small-v2.rdb.zst", false},
+		{"movies", "2024.1", "/data/movies.rdb", false},
+		{"pokec-small", "", "", true},
+		{"pokec-small@v3", "", "", true},
+		{"pokec-large@v1", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			_, version, dataset, err := registry.lookup(tt.reference)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion || dataset.Path != tt.wantPath {
+				t.Errorf("lookup() = %v %v, want %v %v", version, dataset.Path, tt.wantVersion, tt.wantPath)
+			}
+		})
+	}
+}
+
+func Test_resolveRegistryDataset(t *testing.T) {
+	registry := &DatasetRegistry{}
+	if err := yaml.Unmarshal([]byte(testDatasetRegistry), registry); err != nil {
+		t.Fatal(err)
+	}
+	dataset := &DatasetConfig{}
+	if err := yaml.Unmarshal([]byte("name: pokec-small\nversion: v2\n"), dataset); err != nil || dataset.Name != "pokec-small" || dataset.Version != "v2" {
+		t.Fatalf("DatasetConfig.UnmarshalYAML() = %+v, error = %v, want a registry name and version", dataset, err)
+	}
+	loadTimeoutSecs, err := resolveRegistryDataset(dataset, registry)
+	if err != nil {
+		t.Fatalf("resolveRegistryDataset() error = %v", err)
+	}
+	if loadTimeoutSecs != 600 || dataset.Path != "https://example.com/pokec-small-v2.rdb.zst" || !countedDataset(dataset) || dataset.Edges == nil || *dataset.Edges != 121716 {
+		t.Errorf("resolveRegistryDataset() = %v %+v, want the v2 entry", loadTimeoutSecs, dataset)
+	}
+	if got := dataset.Identity.String(); got != "pokec-small@v2" {
+		t.Errorf("DatasetIdentity.String() = %v, want pokec-small@v2", got)
+	}
+
+	mismatch := &DatasetConfig{Name: "pokec-small@v2", Sha256: sha256Hex("other")}
+	if _, err = resolveRegistryDataset(mismatch, registry); err == nil {
+		t.Errorf("resolveRegistryDataset() expected an error for a sha256 that is not the registry one")
+	}
+
+	counted := &DatasetConfig{Name: "pokec-small@v2", Nodes: dataset.Nodes}
+	if _, err = resolveRegistryDataset(counted, registry); err == nil {
+		t.Errorf("resolveRegistryDataset() expected an error for counts that are not the registry ones")
+	}
+
+	plain := &DatasetConfig{Path: "dump.rdb", Identity: &DatasetIdentity{Path: "dump.rdb"}}
+	if countedDataset(plain) {
+		t.Errorf("countedDataset() = true for an RDB dataset without expected counts")
+	}
+}
+
+func Test_registryDatasetReference(t *testing.T) {
+	existing := filepath.Join(t.TempDir(), "graph@2024.rdb")
+	if err := os.WriteFile(existing, []byte("rdb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		dataset string
+		want    bool
+	}{
+		{"pokec-small@v2", true},
+		{"pokec-small", false},
+		{existing, false},
+		{"dumps/graph@2024.rdb", false},
+		{"https://example.com/dump@v2.rdb", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dataset, func(t *testing.T) {
+			dataset := &DatasetConfig{}
+			if err := yaml.Unmarshal([]byte(tt.dataset), dataset); err != nil || dataset.Path != tt.dataset {
+				t.Fatalf("DatasetConfig.UnmarshalYAML() = %+v, error = %v, want a path", dataset, err)
+			}
+			if got := registryDatasetReference(dataset); got != tt.want {
+				t.Errorf("registryDatasetReference() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sameDataset(t *testing.T) {
+	v1 := &DatasetIdentity{Name: "pokec-small", Version: "v1", Sha256: sha256Hex("v1")}
+	v2 := &DatasetIdentity{Name: "pokec-small", Version: "v2", Sha256: sha256Hex("v2")}
+	file := &DatasetIdentity{Path: "dump.rdb", Sha256: sha256Hex("v1")}
+	unknown := &DatasetIdentity{Path: "dump.rdb"}
+	tests := []struct {
+		name      string
+		a, b      *DatasetIdentity
+		wantSame  bool
+		wantKnown bool
+	}{
+		{"same version", v1, v1, true, true},
+		{"other version", v1, v2, false, true},
+		{"same checksum", v1, file, true, true},
+		{"other checksum", v2, file, false, true},
+		{"no checksum", v1, unknown, false, false},
+		{"missing", v1, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same, known := sameDataset(tt.a, tt.b); same != tt.wantSame || known != tt.wantKnown {
+				t.Errorf("sameDataset() = %v %v, want %v %v", same, known, tt.wantSame, tt.wantKnown)
+			}
+		})
+	}
+}
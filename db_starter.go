@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -14,37 +13,6 @@ import (
 	"time"
 )
 
-func DownloadDataset(url string) error {
-	out, err := os.Create("dataset.rdb")
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	fmt.Printf("Downloading dataset...")
-
-	// Get the data
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download dataset, server returned bad status: %s", resp.Status)
-	}
-
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return err
-	}
-
-	fmt.Println("Downloaded dataset")
-	return nil
-}
-
 func CopyFile(src string, dst string) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
@@ -72,19 +40,29 @@ func IsURL(str string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-func prepareDataset(dataset *string) (err error) {
-	if dataset == nil || *dataset == "" {
+// prepareDataset places the RDB file of the dataset, if any, where the database loads it from.
+// URLs are downloaded through the cache. CSV datasets are loaded once the database is started.
+func prepareDataset(dataset *DatasetConfig, cacheDir string) (err error) {
+	if dataset == nil || dataset.Path == "" {
 		return
 	}
 
-	if IsURL(*dataset) {
-		err = DownloadDataset(*dataset)
-		return
+	if IsURL(dataset.Path) {
+		rdb, err := cachedDataset(dataset.Path, dataset.Sha256, cacheDir)
+		if err != nil {
+			return err
+		}
+		return placeDataset(rdb, datasetFile)
 	}
 
+	if err = verifyDatasetFile(dataset.Path, dataset.Sha256); err != nil {
+		return
+	}
+	if isCompressedDataset(dataset.Path) {
+		return extractDataset(dataset.Path, datasetFile)
+	}
 	fmt.Println("Copying dataset")
-	err = CopyFile(*dataset, "dataset.rdb")
-	return
+	return placeDataset(dataset.Path, datasetFile)
 }
 
 func RunFalkorDBContainers(dockerImage string, timeout int, databaseModule string, hasDataset bool) (cancel context.CancelFunc, cmd *exec.Cmd, err error) {
@@ -154,13 +132,13 @@ func RunFalkorDBContainers(dockerImage string, timeout int, databaseModule strin
 	select {
 	case found := <-done:
 		if !found {
-			killDatabase(cmd, cancel, true)
+			killDatabase(cmd, cancel, true, nil)
 			return
 		}
 		fmt.Println("Database accepting connections")
 	case <-time.After(time.Duration(timeout) * time.Second):
 		err = fmt.Errorf("timeout: substring not found within 10 seconds")
-		killDatabase(cmd, cancel, true)
+		killDatabase(cmd, cancel, true, nil)
 		return
 	}
 
@@ -219,13 +197,13 @@ func RunFalkorDBProcess(databaseModule string, timeout int) (cancel context.Canc
 	select {
 	case found := <-done:
 		if !found {
-			killDatabase(cmd, cancel, false)
+			killDatabase(cmd, cancel, false, nil)
 			return
 		}
 		fmt.Println("Database accepting connections")
 	case <-time.After(time.Duration(timeout) * time.Second):
 		err = fmt.Errorf("timeout: substring not found within 10 seconds")
-		killDatabase(cmd, cancel, false)
+		killDatabase(cmd, cancel, false, nil)
 		return
 	}
 
@@ -235,7 +213,7 @@ func RunFalkorDBProcess(databaseModule string, timeout int) (cancel context.Canc
 func startDatabase(yamlConfig *YamlConfig) (cancelFunc context.CancelFunc, cmd *exec.Cmd, isDocker bool, err error) {
 	// Always prefer a docker container if it's available, if a falkordb.so is provided, just use it as a volume
 	if yamlConfig.DockerImage != "" {
-		cancelFunc, cmd, err = RunFalkorDBContainers(yamlConfig.DockerImage, yamlConfig.DBConfig.DatasetLoadTimeoutSecs, yamlConfig.DatabaseModule, yamlConfig.DBConfig.Dataset != nil)
+		cancelFunc, cmd, err = RunFalkorDBContainers(yamlConfig.DockerImage, yamlConfig.DBConfig.DatasetLoadTimeoutSecs, yamlConfig.DatabaseModule, yamlConfig.DBConfig.Dataset != nil && yamlConfig.DBConfig.Dataset.Path != "")
 		isDocker = true
 		return
 	}
@@ -247,12 +225,18 @@ func startDatabase(yamlConfig *YamlConfig) (cancelFunc context.CancelFunc, cmd *
 	return
 }
 
-func killDatabase(cmd *exec.Cmd, cancel context.CancelFunc, isDocker bool) {
+// killDatabase stops the database, and waits for its process to exit. Once the process is monitored, exited is the
+// monitor channel and the monitor reaps the process, otherwise exited is nil and the process is reaped here.
+func killDatabase(cmd *exec.Cmd, cancel context.CancelFunc, isDocker bool, exited <-chan struct{}) {
 	fmt.Println("Ensuring FalkorDB is stopped") // Sounds like a threat
 
 	cancel()
 	cmd.Process.Kill()
-	cmd.Process.Wait()
+	if exited != nil {
+		<-exited
+	} else {
+		cmd.Process.Wait()
+	}
 
 	if isDocker {
 		exec.Command("docker", "rm", "-f", "falkordb").Run()
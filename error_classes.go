@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const (
+	errorClassTimeout             = "timeout"
+	errorClassConnectionReset     = "connection_reset"
+	errorClassSyntaxError         = "syntax_error"
+	errorClassConstraintViolation = "constraint_violation"
+	errorClassOOM                 = "oom"
+	errorClassOther               = "other"
+)
+
+// errorClasses holds all the error classes, in the order they are reported
+var errorClasses = []string{errorClassTimeout, errorClassConnectionReset, errorClassSyntaxError, errorClassConstraintViolation, errorClassOOM, errorClassOther}
+
+// ErrorClassStats aggregates the errors of a single class
+type ErrorClassStats struct {
+	Count uint64 `json:"Count"`
+	// First and last occurrence, in milliseconds since epoch
+	FirstSeen int64 `json:"FirstSeen"`
+	LastSeen  int64 `json:"LastSeen"`
+	// The first distinct error messages
+	Samples []string `json:"Samples"`
+}
+
+// classifyError maps an error returned by the client or by FalkorDB to one of the errorClasses
+func classifyError(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return errorClassTimeout
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return errorClassConnectionReset
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "timed out") || strings.Contains(message, "timeout"):
+		return errorClassTimeout
+	case strings.Contains(message, "connection reset") || strings.Contains(message, "broken pipe") ||
+		strings.Contains(message, "connection refused") || strings.Contains(message, "closed network connection") ||
+		strings.Contains(message, "client is closed"):
+		return errorClassConnectionReset
+	case strings.HasPrefix(message, "oom ") || strings.Contains(message, "out of memory") || strings.Contains(message, "maxmemory"):
+		return errorClassOOM
+	case strings.Contains(message, "constraint"):
+		return errorClassConstraintViolation
+	case strings.Contains(message, "syntax error") || strings.Contains(message, "invalid input") ||
+		strings.Contains(message, "errmsg") || strings.Contains(message, "unknown function") ||
+		strings.Contains(message, "not defined"):
+		return errorClassSyntaxError
+	}
+	return errorClassOther
+}
+
+// recordErrorClass accounts a single error into the provided per class map, keeping up to maxSamples distinct messages
+func recordErrorClass(stats map[string]*ErrorClassStats, class string, message string, timestampMillis int64, maxSamples int) {
+	classStats, ok := stats[class]
+	if !ok {
+		classStats = &ErrorClassStats{FirstSeen: timestampMillis, Samples: []string{}}
+		stats[class] = classStats
+	}
+	classStats.Count++
+	// the errors of the workers are merged one worker at a time, so they are not in order
+	classStats.FirstSeen = min(classStats.FirstSeen, timestampMillis)
+	classStats.LastSeen = max(classStats.LastSeen, timestampMillis)
+	if len(classStats.Samples) >= maxSamples {
+		return
+	}
+	for _, sample := range classStats.Samples {
+		if sample == message {
+			return
+		}
+	}
+	classStats.Samples = append(classStats.Samples, message)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+)
+
+func Test_classifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"context-deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), errorClassTimeout},
+		{"server-timeout", errors.New("Query timed out"), errorClassTimeout},
+		{"eof", io.EOF, errorClassConnectionReset},
+		{"reset", fmt.Errorf("read tcp: %w", syscall.ECONNRESET), errorClassConnectionReset},
+		{"client-closed", errors.New("redis: client is closed"), errorClassConnectionReset},
+		{"syntax", errors.New("errMsg: Invalid input 'X': expected MATCH line: 1, column: 1, offset: 0 errCtx: X errCtxOffset: 0"), errorClassSyntaxError},
+		{"unknown-function", errors.New("Unknown function 'foo'"), errorClassSyntaxError},
+		{"constraint", errors.New("unique constraint violation on node of type User"), errorClassConstraintViolation},
+		{"oom", errors.New("OOM command not allowed when used memory > 'maxmemory'."), errorClassOOM},
+		{"other", errors.New("Type mismatch: expected Integer but was String"), errorClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_recordErrorClass(t *testing.T) {
+	stats := map[string]*ErrorClassStats{}
+	recordErrorClass(stats, errorClassOther, "a", 10, 2)
+	recordErrorClass(stats, errorClassOther, "a", 20, 2)
+	recordErrorClass(stats, errorClassOther, "b", 30, 2)
+	recordErrorClass(stats, errorClassOther, "c", 40, 2)
+	got := stats[errorClassOther]
+	if got.Count != 4 || got.FirstSeen != 10 || got.LastSeen != 40 {
+		t.Errorf("recordErrorClass() = %+v, want Count 4, FirstSeen 10, LastSeen 40", got)
+	}
+	if len(got.Samples) != 2 || got.Samples[0] != "a" || got.Samples[1] != "b" {
+		t.Errorf("recordErrorClass() samples = %v, want [a b]", got.Samples)
+	}
+}
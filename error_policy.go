@@ -0,0 +1,53 @@
+package main
+
+import (
+	"regexp"
+	"time"
+)
+
+// queryErrorPolicy holds the per query error handling settings
+type queryErrorPolicy struct {
+	// errors matching any of these patterns count as a success
+	expectedErrors []*regexp.Regexp
+	// number of retries of transient errors, and the backoff before the first retry ( doubled on every retry )
+	retries      int
+	retryBackoff time.Duration
+	// maximum number of failed requests before stopping the run. 0 means unlimited
+	errorBudget uint64
+}
+
+func newQueryErrorPolicies(queries []Query) []queryErrorPolicy {
+	policies := make([]queryErrorPolicy, len(queries))
+	for i, query := range queries {
+		for _, pattern := range query.ExpectedErrors {
+			// patterns were already validated while parsing the YAML
+			policies[i].expectedErrors = append(policies[i].expectedErrors, regexp.MustCompile(pattern))
+		}
+		policies[i].retries = query.Retries
+		policies[i].retryBackoff = time.Duration(query.RetryBackoffMillis) * time.Millisecond
+		policies[i].errorBudget = query.ErrorBudget
+	}
+	return policies
+}
+
+func (p *queryErrorPolicy) isExpected(err error) bool {
+	for _, pattern := range p.expectedErrors {
+		if pattern.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry returns true if a request that failed on the given attempt ( 0 being the first one ) should be retried.
+// Only transient errors are retried.
+func (p *queryErrorPolicy) shouldRetry(errorClass string, attempt int) bool {
+	if attempt >= p.retries {
+		return false
+	}
+	return errorClass == errorClassTimeout || errorClass == errorClassConnectionReset
+}
+
+func (p *queryErrorPolicy) backoff(attempt int) time.Duration {
+	return p.retryBackoff * time.Duration(1<<attempt)
+}
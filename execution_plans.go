@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"strings"
+)
+
+// representativeQueries generates a single set of parameters for each query, used to capture its execution plans
+func representativeQueries(templates []*queryTemplate, randomIntPadding, randomIntMax int64, sources []*termSource, querySources [][]int) []string {
+	processedQueries := make([]string, len(templates))
+	for i, template := range templates {
+		// the first line of each term source
+		termRows := make([]*termRow, len(querySources[i]))
+		for j, s := range querySources[i] {
+			termRows[j] = sources[s].rows[0]
+		}
+		processedQueries[i] = template.render(randomIntPadding, randomIntMax, termRows)
+	}
+	return processedQueries
+}
+
+// captureExecutionPlans issues the plan command ( GRAPH.EXPLAIN or GRAPH.PROFILE ) once per query and returns the
+// lines of each plan. Failing to retrieve a plan does not stop the benchmark, the plan is simply left empty.
+func captureExecutionPlans(conn *falkordb.FalkorDB, graphName string, command string, queryLabels []string, processedQueries []string, verbose bool) [][]string {
+	plans := make([][]string, len(processedQueries))
+	for i, query := range processedQueries {
+		// the reply is an array of lines, which the client Graph.ExecutionPlan does not handle
+		plan, err := conn.Conn.Do(context.Background(), command, graphName, query).StringSlice()
+		if err != nil {
+			fmt.Printf("Unable to retrieve the %s output of query %s. Continuing anyway. Error: %v\n", command, queryLabels[i], err)
+			continue
+		}
+		plans[i] = plan
+		if verbose {
+			fmt.Printf("%s of query %s:\n%s\n", command, queryLabels[i], strings.Join(plan, "\n"))
+		}
+	}
+	return plans
+}
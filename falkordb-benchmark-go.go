@@ -2,17 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"flag"
 	"fmt"
 	"github.com/FalkorDB/falkordb-go"
 	"golang.org/x/time/rate"
 	"log"
-	"math/rand"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -56,19 +54,34 @@ func getFalkorDBVersion(falkorClient *falkordb.FalkorDB) (version int64, err err
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == planDiffCommand {
+		runPlanDiff(os.Args[2:])
+		return
+	}
+
 	version := flag.Bool("v", false, "Output version and exit")
 	verbose := flag.Bool("verbose", false, "Client verbosity level.")
 	loop := flag.Bool("loop", false, "Run this benchmark in a loop until interrupted")
 	cliUpdateTick := flag.Int("cli_update_tick", 5, "How often should the CLI stdout be updated")
+	serverStatsInterval := flag.Int("server_stats_interval", 5, "How often, in seconds, should the server stats be sampled during the run")
 	yamlConfigFile := flag.String("yaml_config", "", "A .yaml file containing the configuration for this benchmark")
 	dataImportFile := flag.String("data-import-terms", "", "Read field replacement data from file in csv format. each column should start and end with '__' chars. Example __field1__,__field2__.")
 	dataImportMode := flag.String("data-import-terms-mode", "seq", "Either 'seq' or 'rand'.")
 	jsonOutputFile := flag.String("output_file", "benchmark-results.json", "The name of the output file")
 	overrideImage := flag.String("override_image", "", "Override the docker image specified in the yaml file")
 	overrideModule := flag.String("override_module", "", "Override the database module specified in the yaml file")
+	profileQueries := flag.Bool("profile", false, "Run GRAPH.PROFILE once per query after the benchmark, and store the profiles in the result file")
+	errorSamples := flag.Int("error_samples", 5, "Number of distinct error messages kept as samples per query and error class")
+	metricsAddr := flag.String("metrics_addr", "", "If set, expose live Prometheus metrics on this address during the run. Example :9100")
+	cpuProfile := flag.String("cpuprofile", "", "If set, write a cpu profile of the benchmark client itself to this file")
+	pprofAddr := flag.String("pprof_addr", "", "If set, expose the pprof endpoints of the benchmark client on this address. Example localhost:6060")
+	datasetCacheDir := flag.String("dataset_cache_dir", defaultDatasetCacheDir(), "Directory where downloaded datasets are cached, by checksum or URL")
 	flag.Parse()
 
 	printVersion(*version)
+	if *serverStatsInterval <= 0 {
+		log.Fatalf("Invalid server_stats_interval %d, it must be positive", *serverStatsInterval)
+	}
 
 	yamlConfig, err := parseYaml(*yamlConfigFile)
 	if err != nil {
@@ -91,7 +104,27 @@ func main() {
 
 	fmt.Printf("Running in Verbose Mode: %t.\n", *verbose)
 
-	err = prepareDataset(yamlConfig.DBConfig.Dataset)
+	termSourceConfigs := yamlConfig.Parameters.TermSources
+	if *dataImportFile != "" {
+		if *dataImportMode != termModeSeq && *dataImportMode != termModeRand {
+			log.Fatalf("Invalid data-import-terms-mode '%s', either '%s' or '%s' are supported", *dataImportMode, termModeSeq, termModeRand)
+		}
+		termSourceConfigs = append(termSourceConfigs, TermSourceConfig{Name: dataImportTermsSourceName, File: *dataImportFile, Format: termFormatCsv, Mode: *dataImportMode})
+	}
+	termSources := make([]*termSource, len(termSourceConfigs))
+	for i, config := range termSourceConfigs {
+		if config.Cypher != "" {
+			// generated from the graph, once the dataset is loaded
+			continue
+		}
+		fmt.Printf("Reading term source %s from: %s. Using '%s' record read mode.\n", config.Name, config.File, config.Mode)
+		termSources[i], err = loadTermSource(config)
+		if err != nil {
+			log.Fatalf("Unable to read the term source: %v", err)
+		}
+		fmt.Printf("Term source %s has a total of %d distinct lines of terms. Clients pick them on the fly.\n", config.Name, len(termSources[i].rows))
+	}
+	err = prepareDataset(yamlConfig.DBConfig.Dataset, *datasetCacheDir)
 	if err != nil {
 		log.Fatalf("Could not prepare dataset: %v", err)
 	}
@@ -101,10 +134,16 @@ func main() {
 		log.Fatalf("Could not start FalkorDB: %v", err)
 	}
 
+	// every stop path ( signal, error budget, query error, database crash ) goes through the stopper,
+	// so that the clients are drained and the partial results are still reported
+	stopper := newRunStopper()
+	databaseExited := monitorDatabase(cmd, stopper)
+	stopDatabase := func() {
+		killDatabase(cmd, cancelFunc, isDocker, databaseExited)
+	}
+
 	// From here on we can't use log.Fatal in all its forms, as it will not call defer functions
-	defer func() {
-		killDatabase(cmd, cancelFunc, isDocker)
-	}()
+	defer stopDatabase()
 
 	totalQueries := len(yamlConfig.Parameters.Queries) + len(yamlConfig.Parameters.RoQueries)
 	if totalQueries < 1 {
@@ -141,68 +180,41 @@ func main() {
 		fmt.Printf("Running in loop until you hit Ctrl+C\n")
 	}
 
-	randGen := rand.New(rand.NewSource(RandomSeed))
 	randLimit := *yamlConfig.Parameters.RandomIntMax - *yamlConfig.Parameters.RandomIntMin
 
-	var replacementArr []map[string]string
-	dataReplacementEnabled := false
-	if *dataImportFile != "" {
-		fmt.Printf("Reading term data import file from: %s. Using '%s' record read mode.\n", *dataImportFile, *dataImportMode)
-		dataReplacementEnabled = true
-		replacementArr = make([]map[string]string, 0)
+	allQueries, queryIsRO, queryRates, queryNames := convertQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries)
+	queryIds := generateQueryIds(allQueries, queryIsRO, queryNames)
+	queryLabels := generateQueryLabels(allQueries, queryNames)
+	errorPolicies := newQueryErrorPolicies(orderedQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries))
+	timeouts := newQueryTimeouts(orderedQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries))
+	validators := newResultValidators(orderedQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries))
+	totalDifferentCommands, cdf := prepareCommandsDistribution(allQueries, queryRates)
 
-		f, err := os.Open(*dataImportFile)
-		if err != nil {
-			log.Panicln("Unable to read input file "+*dataImportFile, err)
-		}
-		defer func(f *os.File) {
-			err := f.Close()
-			if err != nil {
-			}
-		}(f)
-
-		csvReader := csv.NewReader(f)
-		records, err := csvReader.ReadAll()
-		headers := records[0]
-		rlen := len(records) - 1
-		for i := 0; i < int(yamlConfig.Parameters.NumRequests); i++ {
-			// seq mode
-			recordPos := i % rlen
-			if strings.Compare(*dataImportMode, "rand") == 0 {
-				recordPos = randGen.Intn(rlen)
-			}
-			record := records[recordPos+1]
-			lineMap := make(map[string]string)
-			for j := 0; j < len(headers); j++ {
-				lineMap[headers[j]] = record[j]
-			}
-			replacementArr = append(replacementArr, lineMap)
-		}
-		if err != nil {
-			log.Panicln("Unable to parse file as CSV for "+*dataImportFile, err)
-		}
-		fmt.Printf("There are a total of %d disticint lines of terms. Each line has %d columns. Prepared %d groups of records for the benchmark.\n", rlen, len(headers), len(replacementArr))
+	maxErrorSamples = *errorSamples
+	createRequiredGlobalStructs(totalDifferentCommands)
 
+	if *metricsAddr != "" {
+		metricsServer := startMetricsServer(*metricsAddr, queryIds, queryLabels)
+		defer metricsServer.Close()
 	}
 
-	allQueries, queryIsRO, queryRates := convertQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries)
-	totalDifferentCommands, cdf := prepareCommandsDistribution(allQueries, queryRates)
-
-	createRequiredGlobalStructs(totalDifferentCommands)
+	if *pprofAddr != "" {
+		pprofServer := startPprofServer(*pprofAddr)
+		defer pprofServer.Close()
+	}
 
 	graphs := make([]falkordb.Graph, yamlConfig.Parameters.NumClients)
 	conns := make([]falkordb.FalkorDB, yamlConfig.Parameters.NumClients)
 
 	// a WaitGroup for the goroutines to tell us they've stopped
 	dataPointProcessingWg := sync.WaitGroup{}
-	graphDatapointsChann := make(chan GraphQueryDatapoint, yamlConfig.Parameters.NumClients)
+	statsMergeDone := make(chan struct{})
 
 	// listen for C-c
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	c1 := make(chan os.Signal, 1)
-	signal.Notify(c1, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// until the CLI reads them, the signals stop e.g. the dataset ingestion
+	unwatchSignals := watchSignals(c, stopper)
 
 	graph, falkorConn := getStandaloneConn(yamlConfig.Parameters.Graph, connectionStr, yamlConfig.DBConfig.Password, yamlConfig.DBConfig.TlsCaCertFile, yamlConfig.DBConfig.DatasetLoadTimeoutSecs)
 	falkorDBVersion, err := getFalkorDBVersion(falkorConn)
@@ -216,6 +228,50 @@ func main() {
 		graph.Delete()
 	}()
 
+	var exporter *TimeSeriesExporter
+	if yamlConfig.Exporter != nil {
+		exporter = NewTimeSeriesExporter(yamlConfig.Exporter, *yamlConfig.Name, falkorDBVersion)
+		defer exporter.Close()
+	}
+
+	if dataset := yamlConfig.DBConfig.Dataset; dataset != nil && dataset.Identity != nil {
+		if countedDataset(dataset) {
+			if err = verifyDatasetCounts(graph, dataset); err != nil {
+				log.Panicf("Unable to verify the dataset %s: %v", dataset.Identity, err)
+			}
+			fmt.Printf("Dataset %s loaded, with %d nodes and %d edges\n", dataset.Identity, *dataset.Identity.Nodes, *dataset.Identity.Edges)
+		}
+		testResult.Dataset = dataset.Identity
+	}
+	if yamlConfig.DBConfig.Generate != nil {
+		fmt.Printf("Generating graph %s\n", yamlConfig.Parameters.Graph)
+		testResult.Ingestion, err = generateGraph(graph, yamlConfig.DBConfig.Generate, RandomSeed, stopper)
+		if err != nil && stopper.Stopped() {
+			fmt.Printf("Stopping the benchmark while generating the graph: %s\n", stopper.Reason())
+			return
+		}
+		if err != nil {
+			log.Panicf("Could not generate the graph: %v", err)
+		}
+	}
+	if dataset := yamlConfig.DBConfig.Dataset; dataset != nil && dataset.Csv != nil {
+		fmt.Printf("Loading the CSV dataset into graph %s with %d connections\n", yamlConfig.Parameters.Graph, dataset.Csv.Concurrency)
+		testResult.Ingestion, err = loadCsvDataset(graph, dataset.Csv, func() *falkordb.Graph {
+			loaderGraph, _ := getStandaloneConn(yamlConfig.Parameters.Graph, connectionStr, yamlConfig.DBConfig.Password, yamlConfig.DBConfig.TlsCaCertFile, yamlConfig.DBConfig.DatasetLoadTimeoutSecs)
+			return loaderGraph
+		}, stopper)
+		if err != nil && stopper.Stopped() {
+			fmt.Printf("Stopping the benchmark while loading the CSV dataset: %s\n", stopper.Reason())
+			return
+		}
+		if err != nil {
+			log.Panicf("Could not load the CSV dataset: %v", err)
+		}
+	}
+	if testResult.Ingestion != nil {
+		printIngestionSummary(testResult.Ingestion, os.Stdout, "## Dataset ingestion table\n")
+	}
+
 	for _, command := range yamlConfig.DBConfig.InitCommands {
 		interfaceArray := make([]interface{}, len(command))
 		for i, v := range command {
@@ -228,21 +284,54 @@ func main() {
 		}
 	}
 
+	for i, config := range termSourceConfigs {
+		if config.Cypher == "" {
+			continue
+		}
+		fmt.Printf("Sampling term source %s from the graph with: %s. Using '%s' record read mode.\n", config.Name, config.Cypher, config.Mode)
+		termSources[i], err = loadCypherTermSource(config, graph, RandomSeed+int64(i))
+		if err != nil {
+			log.Panicf("Unable to sample the term source: %v", err)
+		}
+		fmt.Printf("Term source %s has a total of %d distinct lines of terms. Clients pick them on the fly.\n", config.Name, len(termSources[i].rows))
+	}
+	var orderedQueryNames []string
+	for _, query := range orderedQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries) {
+		orderedQueryNames = append(orderedQueryNames, query.Name)
+	}
+	querySources, queryPlaceholders, err := bindTermSources(termSources, orderedQueryNames, yamlConfig.Parameters.NumClients)
+	if err != nil {
+		log.Panicf("Invalid term sources: %v", err)
+	}
+	queryTemplates := newQueryTemplates(allQueries, queryPlaceholders)
+
+	// plans are captured once the init commands ( e.g. index creation ) were run
+	planQueries := representativeQueries(queryTemplates, *yamlConfig.Parameters.RandomIntMin, randLimit, termSources, querySources)
+	executionPlans := captureExecutionPlans(falkorConn, yamlConfig.Parameters.Graph, "GRAPH.EXPLAIN", queryLabels, planQueries, *verbose)
+
 	tick := time.NewTicker(time.Duration(*cliUpdateTick) * time.Second)
 
-	dataPointProcessingWg.Add(1)
-	go processGraphDatapointsChannel(graphDatapointsChann, c1, yamlConfig.Parameters.NumRequests, &dataPointProcessingWg, &instantHistogramsResetMutex)
+	sampler := newServerStatsSampler(falkorConn, graph, yamlConfig.Parameters.Graph)
+	serverStatsBefore := sampler.Sample(time.Now(), true)
 
-	panicChannel := make(chan bool)
-	defer close(panicChannel)
+	dataPointProcessingWg.Add(1)
+	statsMerger := newStatsMerger(yamlConfig.Parameters.NumClients, &instantHistogramsResetMutex, errorPolicies, queryLabels, stopper)
+	go statsMerger.run(statsMergeDone, &dataPointProcessingWg)
 
 	// Total commands to be issue per client. Equal for all clients, except for the last one ( see comment bellow )
 	clientTotalCmds := samplesPerClient
+	processSampler := startProcessStatsSampler(cmd, isDocker, time.Duration(*cliUpdateTick)*time.Second)
+	sampler.Start(time.Duration(*serverStatsInterval)*time.Second, exporter)
+	if *cpuProfile != "" {
+		stopCPUProfile := startCPUProfile(*cpuProfile)
+		defer stopCPUProfile()
+	}
 	startTime := time.Now()
+	clientSampler := newClientStatsSampler(startTime)
 	for clientId := 0; uint64(clientId) < yamlConfig.Parameters.NumClients; clientId++ {
 		wg.Add(1)
 
-		graphPtr, connsPtr := getStandaloneConn(yamlConfig.Parameters.Graph, connectionStr, yamlConfig.DBConfig.Password, yamlConfig.DBConfig.TlsCaCertFile, 5)
+		graphPtr, connsPtr := getStandaloneConn(yamlConfig.Parameters.Graph, connectionStr, yamlConfig.DBConfig.Password, yamlConfig.DBConfig.TlsCaCertFile, workerReadTimeout(timeouts))
 		graphs[clientId] = *graphPtr
 		conns[clientId] = *connsPtr
 
@@ -252,13 +341,24 @@ func main() {
 		if uint64(clientId) == (yamlConfig.Parameters.NumClients - uint64(1)) {
 			clientTotalCmds = samplesPerClientRemainder + samplesPerClient
 		}
-		cmdStartPos := uint64(clientId) * samplesPerClient
-		go ingestionRoutine(&graphs[clientId], yamlConfig.ContinueOnError, allQueries, queryIsRO, cdf, *yamlConfig.Parameters.RandomIntMin, randLimit, clientTotalCmds, *loop, *verbose, &wg, useRateLimiter, rateLimiter, graphDatapointsChann, dataReplacementEnabled, replacementArr, cmdStartPos, panicChannel)
+		pickers := make([]*termsPicker, len(termSources))
+		for s, source := range termSources {
+			pickers[s] = newTermsPicker(source, uint64(clientId), yamlConfig.Parameters.NumClients, uint64(clientId)*samplesPerClient, RandomSeed+int64(clientId*len(termSources)+s))
+		}
+		go ingestionRoutine(&graphs[clientId], yamlConfig.ContinueOnError, allQueries, queryTemplates, queryIsRO, cdf, errorPolicies, timeouts, validators, *yamlConfig.Parameters.RandomIntMin, randLimit, clientTotalCmds, *loop, *verbose, &wg, useRateLimiter, rateLimiter, statsMerger.workers[clientId], pickers, querySources, stopper)
 	}
 
 	// enter the update loopUpdateCLIUpdateCLI
-	updateCLI(startTime, tick, c, yamlConfig.Parameters.NumRequests, *loop, panicChannel)
+	unwatchSignals()
+	updateCLI(startTime, tick, c, yamlConfig.Parameters.NumRequests, *loop, stopper, queryIds, queryLabels, exporter, clientSampler)
+	// a second C-c while draining stops the database and exits right away
+	escalateSignals(c, stopper, stopDatabase)
+	if stopper.Reason() != stopReasonCompleted {
+		fmt.Printf("\nStopping the benchmark: %s. Waiting for the in-flight requests to finish...\n", stopper.Reason())
+	}
 
+	// let the clients finish their in-flight requests
+	wg.Wait()
 	endTime := time.Now()
 	duration := time.Since(startTime)
 
@@ -268,24 +368,92 @@ func main() {
 	}
 
 	//wait for all stats to be processed
+	close(statsMergeDone)
 	dataPointProcessingWg.Wait()
 
+	testResult.ClientStatsSummary = clientSampler.Summary(duration, yamlConfig.Parameters.NumClients)
+	sampler.Stop()
+	serverStatsAfter := sampler.Sample(time.Now(), true)
+	testResult.DatabaseProcessStats = processSampler.Stop(totalCommands)
+
+	// profiling runs the queries, so it's done after the measured phase
+	profiles := make([][]string, len(allQueries))
+	if *profileQueries {
+		profiles = captureExecutionPlans(falkorConn, yamlConfig.Parameters.Graph, "GRAPH.PROFILE", queryLabels, planQueries, *verbose)
+	}
+
 	testResult.FillDurationInfo(startTime, endTime, duration)
-	testResult.BenchmarkFullyRun = totalCommands == yamlConfig.Parameters.NumRequests
+	testResult.StopReason = stopper.Reason()
+	testResult.BenchmarkFullyRun = testResult.StopReason == stopReasonCompleted && totalCommands == yamlConfig.Parameters.NumRequests
 	testResult.IssuedCommands = totalCommands
-	overallGraphInternalLatencies, internalLatencyMap := GetOverallLatencies(allQueries, serverSidePerQueryGraphInternalTimeOverallLatencies, serverSideAllQueriesGraphInternalTimeOverallLatencies)
-	overallClientLatencies, clientLatencyMap := GetOverallLatencies(allQueries, clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies)
+	graphInternalLatencies, internalLatencyMap := GetOverallLatencies(serverSidePerQueryGraphInternalTimeOverallLatencies, serverSideAllQueriesGraphInternalTimeOverallLatencies)
+	clientLatencies, clientLatencyMap := GetOverallLatencies(clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies)
 	relativeLatencyDiff, absoluteLatencyDiff := GenerateInternalExternalRatioLatencies(internalLatencyMap, clientLatencyMap)
-	testResult.OverallClientLatencies = overallClientLatencies
-	testResult.OverallGraphInternalLatencies = overallGraphInternalLatencies
+	failedClientLatencies, failedClientLatencyMap := GetOverallLatencies(clientSidePerQueryFailedLatencies, clientSideAllQueriesFailedLatencies)
+	queryRatesPerQuery, overallQueryRate := GetOverallRates(duration, totalCommandsPerQuery, totalCommands)
+	totalsPerQuery, overallTotals := GetTotals(totalCommandsPerQuery, errorsPerQuery, totalNodesCreatedPerQuery, totalNodesDeletedPerQuery, totalLabelsAddedPerQuery, totalPropertiesSetPerQuery, totalRelationshipsCreatedPerQuery, totalRelationshipsDeletedPerQuery, emptyResultsetsPerQuery, totalRowsPerQuery, totalReplyBytesPerQuery)
+	testResult.Queries = make([]QueryStats, len(allQueries))
+	for i, query := range allQueries {
+		testResult.Queries[i] = QueryStats{
+			Id:                     queryIds[i],
+			Name:                   queryNames[i],
+			Query:                  query,
+			ReadOnly:               queryIsRO[i],
+			Ratio:                  queryRates[i],
+			Totals:                 totalsPerQuery[i],
+			QueryRate:              queryRatesPerQuery[i],
+			ClientLatencies:        clientLatencies[i],
+			GraphInternalLatencies: graphInternalLatencies[i],
+			FailedClientLatencies:  failedClientLatencies[i],
+			ErrorClasses:           errorClassStatsPerQuery[i],
+			ExpectedErrors:         expectedErrorsPerQuery[i],
+			FailedRequests:         failedRequestsPerQuery[i],
+			Retries:                NewRetryStats(retryAttemptsPerQuery[i], retryErrorsPerQuery[i], recoveredRequestsPerQuery[i], histogramOrEmpty(clientSidePerQueryRetryLatencies[i])),
+		}
+		testResult.Queries[i].ResultSize = NewResultSizeStats(rowsPerRequestPerQuery[i], replyBytesPerRequestPerQuery[i])
+		testResult.Queries[i].ExecutionPlan = QueryExecutionPlan{Query: planQueries[i], Explain: executionPlans[i], Profile: profiles[i]}
+		testResult.Queries[i].PlanCache = NewPlanCacheStats(cachedExecutionsPerQuery[i], clientSidePerQueryUncachedLatencies[i], serverSidePerQueryUncachedLatencies[i], clientSidePerQueryCachedLatencies[i], serverSidePerQueryCachedLatencies[i])
+		if validators[i] != nil {
+			testResult.Queries[i].Validation = validationStatsPerQuery[i]
+		}
+	}
+	testResult.OverallClientLatencies = map[string]interface{}{"Total": clientLatencyMap}
+	testResult.OverallGraphInternalLatencies = map[string]interface{}{"Total": internalLatencyMap}
+	testResult.OverallFailedClientLatencies = map[string]interface{}{"Total": failedClientLatencyMap}
 	testResult.AbsoluteInternalExternalLatencyDiff = absoluteLatencyDiff
 	testResult.RelativeInternalExternalLatencyDiff = relativeLatencyDiff
-	testResult.OverallQueryRates = GetOverallRatesMap(duration, allQueries, clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies)
+	testResult.OverallQueryRates = map[string]interface{}{"Total": overallQueryRate}
 	testResult.DBSpecificConfigs = GetDBConfigsMap(falkorDBVersion)
-	testResult.Totals = GetTotalsMap(allQueries, clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies, errorsPerQuery, totalNodesCreatedPerQuery, totalNodesDeletedPerQuery, totalLabelsAddedPerQuery, totalPropertiesSetPerQuery, totalRelationshipsCreatedPerQuery, totalRelationshipsDeletedPerQuery)
+	testResult.Totals = map[string]interface{}{"Total": overallTotals}
+	testResult.ErrorClasses = totalErrorClassStats
+	testResult.ExpectedErrors = CountTotal(expectedErrorsPerQuery)
+	testResult.FailedRequests = CountTotal(failedRequestsPerQuery)
+	writeQueries := uint64(0)
+	for i := range allQueries {
+		if !queryIsRO[i] {
+			writeQueries += totalCommandsPerQuery[i]
+		}
+	}
+	testResult.ServerRunTimeStats = sampler.Samples()
+	testResult.ClientRunTimeStats = clientSampler.samples
+	testResult.ServerStatsSummary = NewServerStatsSummary(serverStatsBefore, serverStatsAfter, writeQueries)
+	testResult.ResultSize = NewResultSizeStats(rowsPerRequestAllQueries, replyBytesPerRequestAllQueries)
+	testResult.PlanCache = NewPlanCacheStats(CountTotal(cachedExecutionsPerQuery), clientSideAllQueriesUncachedLatencies, serverSideAllQueriesUncachedLatencies, clientSideAllQueriesCachedLatencies, serverSideAllQueriesCachedLatencies)
+	if totalValidationStats.Checked > 0 {
+		testResult.Validation = totalValidationStats
+	}
+	testResult.Retries = NewRetryStats(CountTotal(retryAttemptsPerQuery), CountTotal(retryErrorsPerQuery), CountTotal(recoveredRequestsPerQuery), histogramOrEmpty(clientSideAllQueriesRetryLatencies))
 
 	// final merge of pending stats
-	printFinalSummary(allQueries, totalCommands, duration)
+	printFinalSummary(queryLabels, totalCommands, duration)
+	printServerStatsSummary(testResult.ServerStatsSummary, os.Stdout, "## Server stats before and after the benchmark\n")
+	printClientStatsSummary(testResult.ClientStatsSummary, os.Stdout, "## Benchmark client resources\n")
+	if testResult.DatabaseProcessStats != nil {
+		printProcessStatsSummary(testResult.DatabaseProcessStats, os.Stdout, "## Database process resources table\n")
+	}
+	if !testResult.BenchmarkFullyRun {
+		fmt.Printf("Benchmark did not fully run ( stop reason: %s ), the results above are partial\n", testResult.StopReason)
+	}
 
 	saveJsonResult(testResult, *jsonOutputFile)
 }
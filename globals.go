@@ -8,10 +8,31 @@ import (
 )
 
 var totalCommands uint64
+var totalCommandsPerQuery []uint64
 var totalEmptyResultsets uint64
 var totalErrors uint64
 var errorsPerQuery []uint64
 
+var expectedErrorsPerQuery []uint64
+var failedRequestsPerQuery []uint64
+var retryAttemptsPerQuery []uint64
+var retryErrorsPerQuery []uint64
+var recoveredRequestsPerQuery []uint64
+
+// maximum number of distinct error messages kept per query and error class
+var maxErrorSamples = 5
+var errorClassStatsPerQuery []map[string]*ErrorClassStats
+var totalErrorClassStats map[string]*ErrorClassStats
+
+// only updated by the datapoints processor
+var validationStatsPerQuery []*ValidationStats
+var totalValidationStats *ValidationStats
+
+var emptyResultsetsPerQuery []uint64
+var cachedExecutionsPerQuery []uint64
+var totalRowsPerQuery []uint64
+var totalReplyBytesPerQuery []uint64
+
 var totalNodesCreated uint64
 var totalNodesDeleted uint64
 var totalLabelsAdded uint64
@@ -28,6 +49,9 @@ var totalRelationshipsDeletedPerQuery []uint64
 
 var randIntPlaceholder = "__rand_int__"
 
+// time spent by the workers blocked recording their datapoints
+var datapointsRecordBlockedNanos uint64
+
 // no locking is required when using the histograms. data is duplicated on the instant and overall histograms
 var clientSideAllQueriesOverallLatencies *hdrhistogram.Histogram
 var serverSideAllQueriesGraphInternalTimeOverallLatencies *hdrhistogram.Histogram
@@ -35,15 +59,84 @@ var serverSideAllQueriesGraphInternalTimeOverallLatencies *hdrhistogram.Histogra
 var clientSidePerQueryOverallLatencies []*hdrhistogram.Histogram
 var serverSidePerQueryGraphInternalTimeOverallLatencies []*hdrhistogram.Histogram
 
+// failed requests are kept apart, and have no graph internal time
+var clientSideAllQueriesFailedLatencies *hdrhistogram.Histogram
+var clientSidePerQueryFailedLatencies []*hdrhistogram.Histogram
+
+// retries are kept apart from the first attempts. created on first use, most runs have no retries
+var clientSideAllQueriesRetryLatencies *hdrhistogram.Histogram
+var clientSidePerQueryRetryLatencies []*hdrhistogram.Histogram
+
+// rows and approximate reply size of each successful request. only updated by the datapoints processor
+var rowsPerRequestAllQueries *hdrhistogram.Histogram
+var rowsPerRequestPerQuery []*hdrhistogram.Histogram
+var replyBytesPerRequestAllQueries *hdrhistogram.Histogram
+var replyBytesPerRequestPerQuery []*hdrhistogram.Histogram
+
+// latencies of the successful requests, split by the use of a cached execution plan. only updated by the datapoints
+// processor, and created on first use
+var clientSideAllQueriesCachedLatencies *hdrhistogram.Histogram
+var clientSideAllQueriesUncachedLatencies *hdrhistogram.Histogram
+var serverSideAllQueriesCachedLatencies *hdrhistogram.Histogram
+var serverSideAllQueriesUncachedLatencies *hdrhistogram.Histogram
+var clientSidePerQueryCachedLatencies []*hdrhistogram.Histogram
+var clientSidePerQueryUncachedLatencies []*hdrhistogram.Histogram
+var serverSidePerQueryCachedLatencies []*hdrhistogram.Histogram
+var serverSidePerQueryUncachedLatencies []*hdrhistogram.Histogram
+
 // this mutex does not affect any of the client go-routines ( it's only to sync between main thread and datapoints processor go-routines )
 var instantHistogramsResetMutex sync.Mutex
 var clientSideAllQueriesInstantLatencies *hdrhistogram.Histogram
 var serverSideAllQueriesGraphInternalTimeInstantLatencies *hdrhistogram.Histogram
 
+var clientSidePerQueryInstantLatencies []*hdrhistogram.Histogram
+var serverSidePerQueryGraphInternalTimeInstantLatencies []*hdrhistogram.Histogram
+
 const Inf = rate.Limit(math.MaxFloat64)
 
+// result sizes are recorded with less precision than the latencies, which keeps their histograms small
+const maxRowsPerRequest = 1000000000
+const maxReplyBytesPerRequest = 1000000000000
+
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(1, 90000000000, 4)
+}
+
+// emptyHistogram stands for the histograms created on first use that were never recorded into
+var emptyHistogram = hdrhistogram.New(1, 2, 1)
+
+// histogramOnFirstUse returns the histogram, creating it when it's recorded into for the first time
+func histogramOnFirstUse(histogram **hdrhistogram.Histogram, newHistogram func() *hdrhistogram.Histogram) *hdrhistogram.Histogram {
+	if *histogram == nil {
+		*histogram = newHistogram()
+	}
+	return *histogram
+}
+
+// histogramOrEmpty returns the histogram to read, an empty one when it was never created
+func histogramOrEmpty(histogram *hdrhistogram.Histogram) *hdrhistogram.Histogram {
+	if histogram == nil {
+		return emptyHistogram
+	}
+	return histogram
+}
+
 func createRequiredGlobalStructs(totalDifferentCommands int) {
+	totalCommandsPerQuery = make([]uint64, totalDifferentCommands)
 	errorsPerQuery = make([]uint64, totalDifferentCommands)
+	expectedErrorsPerQuery = make([]uint64, totalDifferentCommands)
+	failedRequestsPerQuery = make([]uint64, totalDifferentCommands)
+	retryAttemptsPerQuery = make([]uint64, totalDifferentCommands)
+	retryErrorsPerQuery = make([]uint64, totalDifferentCommands)
+	recoveredRequestsPerQuery = make([]uint64, totalDifferentCommands)
+	errorClassStatsPerQuery = make([]map[string]*ErrorClassStats, totalDifferentCommands)
+	totalErrorClassStats = map[string]*ErrorClassStats{}
+	validationStatsPerQuery = make([]*ValidationStats, totalDifferentCommands)
+	totalValidationStats = &ValidationStats{Samples: []string{}}
+	emptyResultsetsPerQuery = make([]uint64, totalDifferentCommands)
+	cachedExecutionsPerQuery = make([]uint64, totalDifferentCommands)
+	totalRowsPerQuery = make([]uint64, totalDifferentCommands)
+	totalReplyBytesPerQuery = make([]uint64, totalDifferentCommands)
 	totalNodesCreatedPerQuery = make([]uint64, totalDifferentCommands)
 	totalNodesDeletedPerQuery = make([]uint64, totalDifferentCommands)
 	totalLabelsAddedPerQuery = make([]uint64, totalDifferentCommands)
@@ -55,12 +148,37 @@ func createRequiredGlobalStructs(totalDifferentCommands int) {
 	clientSideAllQueriesInstantLatencies = hdrhistogram.New(1, 90000000000, 4)
 	serverSideAllQueriesGraphInternalTimeOverallLatencies = hdrhistogram.New(1, 90000000000, 4)
 	serverSideAllQueriesGraphInternalTimeInstantLatencies = hdrhistogram.New(1, 90000000000, 4)
+	clientSideAllQueriesFailedLatencies = hdrhistogram.New(1, 90000000000, 4)
+	clientSideAllQueriesRetryLatencies = nil
+	rowsPerRequestAllQueries = hdrhistogram.New(1, maxRowsPerRequest, 3)
+	replyBytesPerRequestAllQueries = hdrhistogram.New(1, maxReplyBytesPerRequest, 3)
+	clientSideAllQueriesCachedLatencies = nil
+	clientSideAllQueriesUncachedLatencies = nil
+	serverSideAllQueriesCachedLatencies = nil
+	serverSideAllQueriesUncachedLatencies = nil
 
 	clientSidePerQueryOverallLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
 	serverSidePerQueryGraphInternalTimeOverallLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	clientSidePerQueryFailedLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	clientSidePerQueryRetryLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	rowsPerRequestPerQuery = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	replyBytesPerRequestPerQuery = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	clientSidePerQueryCachedLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	clientSidePerQueryUncachedLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	serverSidePerQueryCachedLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	serverSidePerQueryUncachedLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	clientSidePerQueryInstantLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
+	serverSidePerQueryGraphInternalTimeInstantLatencies = make([]*hdrhistogram.Histogram, totalDifferentCommands)
 	for i := 0; i < totalDifferentCommands; i++ {
+		errorClassStatsPerQuery[i] = map[string]*ErrorClassStats{}
+		validationStatsPerQuery[i] = &ValidationStats{Samples: []string{}}
 		clientSidePerQueryOverallLatencies[i] = hdrhistogram.New(1, 90000000000, 4)
 		serverSidePerQueryGraphInternalTimeOverallLatencies[i] = hdrhistogram.New(1, 90000000000, 4)
+		clientSidePerQueryFailedLatencies[i] = hdrhistogram.New(1, 90000000000, 4)
+		rowsPerRequestPerQuery[i] = hdrhistogram.New(1, maxRowsPerRequest, 3)
+		replyBytesPerRequestPerQuery[i] = hdrhistogram.New(1, maxReplyBytesPerRequest, 3)
+		clientSidePerQueryInstantLatencies[i] = hdrhistogram.New(1, 90000000000, 4)
+		serverSidePerQueryGraphInternalTimeInstantLatencies[i] = hdrhistogram.New(1, 90000000000, 4)
 	}
 }
 
@@ -68,5 +186,9 @@ func resetInstantHistograms() {
 	instantHistogramsResetMutex.Lock()
 	clientSideAllQueriesInstantLatencies.Reset()
 	serverSideAllQueriesGraphInternalTimeInstantLatencies.Reset()
+	for i := range clientSidePerQueryInstantLatencies {
+		clientSidePerQueryInstantLatencies[i].Reset()
+		serverSidePerQueryGraphInternalTimeInstantLatencies[i].Reset()
+	}
 	instantHistogramsResetMutex.Unlock()
 }
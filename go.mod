@@ -1,12 +1,13 @@
 module github.com/FalkorDB/falkordb-benchmark-go
 
-go 1.21
+go 1.22
 
 toolchain go1.22.2
 
 require (
 	github.com/FalkorDB/falkordb-go v0.1.0
 	github.com/HdrHistogram/hdrhistogram-go v1.1.2
+	github.com/klauspost/compress v1.18.0
 	github.com/olekukonko/tablewriter v0.0.5
 	golang.org/x/time v0.5.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"sync"
+	"time"
+)
+
+const (
+	ingestionSourceGenerate = "generate"
+	ingestionSourceCsv      = "csv"
+)
+
+// IngestionStats reports how the dataset was loaded into the database, as a benchmark of its own
+type IngestionStats struct {
+	// generate or csv
+	Source         string               `json:"Source"`
+	BatchSize      int                  `json:"BatchSize"`
+	Concurrency    int                  `json:"Concurrency"`
+	Steps          []IngestionStepStats `json:"Steps"`
+	Entities       uint64               `json:"Entities"`
+	DurationMillis int64                `json:"DurationMillis"`
+	EntitiesPerSec float64              `json:"EntitiesPerSec"`
+}
+
+// IngestionStepStats holds the load of a single label, relationship type, or of the indices
+type IngestionStepStats struct {
+	Name           string  `json:"Name"`
+	File           string  `json:"File,omitempty"`
+	Entities       uint64  `json:"Entities"`
+	Batches        uint64  `json:"Batches"`
+	DurationMillis int64   `json:"DurationMillis"`
+	EntitiesPerSec float64 `json:"EntitiesPerSec"`
+	// Client side latencies of the write queries, in milliseconds
+	BatchLatencies map[string]float64 `json:"BatchLatencies"`
+}
+
+// ingestionStep records the batches of a step, which can be issued concurrently
+type ingestionStep struct {
+	mu        sync.Mutex
+	stats     IngestionStepStats
+	latencies *hdrhistogram.Histogram
+	startT    time.Time
+}
+
+func newIngestionStep(name, file string) *ingestionStep {
+	return &ingestionStep{
+		stats:     IngestionStepStats{Name: name, File: file},
+		latencies: hdrhistogram.New(1, 90000000000, 4),
+		startT:    time.Now(),
+	}
+}
+
+func (s *ingestionStep) record(entities int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Entities += uint64(entities)
+	s.stats.Batches++
+	s.latencies.RecordValue(latency.Microseconds())
+}
+
+func (s *ingestionStep) finish() IngestionStepStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	duration := time.Since(s.startT)
+	s.stats.DurationMillis = duration.Milliseconds()
+	s.stats.EntitiesPerSec = float64(s.stats.Entities) / duration.Seconds()
+	_, s.stats.BatchLatencies = generateLatenciesMap(s.latencies)
+	return s.stats
+}
+
+func newIngestionStats(source string, batchSize, concurrency int, steps []IngestionStepStats, duration time.Duration) *IngestionStats {
+	stats := &IngestionStats{Source: source, BatchSize: batchSize, Concurrency: concurrency, Steps: steps, DurationMillis: duration.Milliseconds()}
+	for _, step := range steps {
+		stats.Entities += step.Entities
+	}
+	stats.EntitiesPerSec = float64(stats.Entities) / duration.Seconds()
+	return stats
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const metricsNamespace = "falkordb_benchmark"
+
+// quantiles exposed on the summary metrics, in percent ( as expected by hdrhistogram )
+var metricsQuantiles = []float64{0.0, 50.0, 95.0, 99.0, 99.9, 100.0}
+
+// startMetricsServer exposes the running benchmark counters and latency summaries in the
+// Prometheus/OpenMetrics text format on the provided address ( e.g. ":9100" ).
+// It uses a dedicated mux so that nothing else registered on the default one gets exposed.
+func startMetricsServer(addr string, queryIds []string, queryLabels []string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, queryIds, queryLabels)
+	})
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics endpoint on %s stopped: %v", addr, err)
+		}
+	}()
+	fmt.Printf("Exposing live metrics on http://%s/metrics\n", addr)
+	return server
+}
+
+func writeMetrics(w io.Writer, queryIds []string, queryLabels []string) {
+	writeCounter(w, "commands_total", "Total commands issued.", atomic.LoadUint64(&totalCommands))
+	writeCounter(w, "errors_total", "Total commands that returned an error.", atomic.LoadUint64(&totalErrors))
+	writeCounter(w, "nodes_created_total", "Total nodes created.", atomic.LoadUint64(&totalNodesCreated))
+	writeCounter(w, "nodes_deleted_total", "Total nodes deleted.", atomic.LoadUint64(&totalNodesDeleted))
+	writeCounter(w, "relationships_created_total", "Total relationships created.", atomic.LoadUint64(&totalRelationshipsCreated))
+	writeCounter(w, "relationships_deleted_total", "Total relationships deleted.", atomic.LoadUint64(&totalRelationshipsDeleted))
+
+	fmt.Fprintf(w, "# HELP %s_query_ops_total Total commands issued per query.\n", metricsNamespace)
+	fmt.Fprintf(w, "# TYPE %s_query_ops_total counter\n", metricsNamespace)
+	for i, queryId := range queryIds {
+		fmt.Fprintf(w, "%s_query_ops_total{%s} %d\n", metricsNamespace, queryMetricLabels(queryId, queryLabels[i]), atomic.LoadUint64(&totalCommandsPerQuery[i]))
+	}
+
+	fmt.Fprintf(w, "# HELP %s_query_errors_total Total errors per query.\n", metricsNamespace)
+	fmt.Fprintf(w, "# TYPE %s_query_errors_total counter\n", metricsNamespace)
+	for i, queryId := range queryIds {
+		fmt.Fprintf(w, "%s_query_errors_total{%s} %d\n", metricsNamespace, queryMetricLabels(queryId, queryLabels[i]), atomic.LoadUint64(&errorsPerQuery[i]))
+	}
+
+	// the histograms are shared with the datapoints processor go-routine
+	instantHistogramsResetMutex.Lock()
+	defer instantHistogramsResetMutex.Unlock()
+	writeSummary(w, "client_latency_seconds", "Client side latency including RTT, of the successful requests.", queryIds, queryLabels, clientSidePerQueryOverallLatencies, clientSideAllQueriesOverallLatencies)
+	writeSummary(w, "client_failed_latency_seconds", "Client side latency including RTT, of the requests that returned an error.", queryIds, queryLabels, clientSidePerQueryFailedLatencies, clientSideAllQueriesFailedLatencies)
+	writeSummary(w, "graph_internal_latency_seconds", "FalkorDB reported internal execution time.", queryIds, queryLabels, serverSidePerQueryGraphInternalTimeOverallLatencies, serverSideAllQueriesGraphInternalTimeOverallLatencies)
+}
+
+func writeCounter(w io.Writer, name, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", metricsNamespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s counter\n", metricsNamespace, name)
+	fmt.Fprintf(w, "%s_%s %d\n", metricsNamespace, name, value)
+}
+
+func writeSummary(w io.Writer, name, help string, queryIds []string, queryLabels []string, perQueryHistograms []*hdrhistogram.Histogram, totalsHistogram *hdrhistogram.Histogram) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", metricsNamespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s summary\n", metricsNamespace, name)
+	for i, queryId := range queryIds {
+		writeSummaryLines(w, name, queryMetricLabels(queryId, queryLabels[i]), perQueryHistograms[i])
+	}
+	writeSummaryLines(w, name, queryMetricLabels("Total", "Total"), totalsHistogram)
+}
+
+// histograms are recorded in microseconds, prometheus expects seconds
+func writeSummaryLines(w io.Writer, name, labels string, histogram *hdrhistogram.Histogram) {
+	for _, quantile := range metricsQuantiles {
+		fmt.Fprintf(w, "%s_%s{%s,quantile=\"%g\"} %g\n", metricsNamespace, name, labels, quantile/100.0, float64(histogram.ValueAtQuantile(quantile))/1e6)
+	}
+	count := histogram.TotalCount()
+	fmt.Fprintf(w, "%s_%s_sum{%s} %g\n", metricsNamespace, name, labels, histogram.Mean()*float64(count)/1e6)
+	fmt.Fprintf(w, "%s_%s_count{%s} %d\n", metricsNamespace, name, labels, count)
+}
+
+func queryMetricLabels(queryId, queryLabel string) string {
+	return fmt.Sprintf("query_id=\"%s\",query=\"%s\"", escapeMetricLabel(queryId), escapeMetricLabel(queryLabel))
+}
+
+var metricLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeMetricLabel(value string) string {
+	return metricLabelReplacer.Replace(value)
+}
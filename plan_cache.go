@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// PlanCacheStats splits the latencies of the executions that reused a cached execution plan from the ones that did not
+type PlanCacheStats struct {
+	Executions       uint64  `json:"Executions"`
+	CachedExecutions uint64  `json:"CachedExecutions"`
+	CachedRatio      float64 `json:"CachedRatio"`
+	// Latencies of the executions that built their execution plan
+	UncachedClientLatencies        map[string]float64 `json:"UncachedClientLatencies"`
+	UncachedGraphInternalLatencies map[string]float64 `json:"UncachedGraphInternalLatencies"`
+	CachedClientLatencies          map[string]float64 `json:"CachedClientLatencies"`
+	CachedGraphInternalLatencies   map[string]float64 `json:"CachedGraphInternalLatencies"`
+}
+
+// recordPlanCache accounts whether the successful requests of a worker interval used a cached execution plan. The
+// histograms are created on first use, as a query usually has either cached or uncached executions only.
+func recordPlanCache(cmdPos int, stats *workerQueryStats) {
+	cachedExecutionsPerQuery[cmdPos] += stats.cachedExecutions
+	if len(stats.cachedClientLatencies) > 0 {
+		stats.cachedClientLatencies.mergeInto(histogramOnFirstUse(&clientSidePerQueryCachedLatencies[cmdPos], newLatencyHistogram), histogramOnFirstUse(&clientSideAllQueriesCachedLatencies, newLatencyHistogram))
+		stats.cachedGraphInternalLatencies.mergeInto(histogramOnFirstUse(&serverSidePerQueryCachedLatencies[cmdPos], newLatencyHistogram), histogramOnFirstUse(&serverSideAllQueriesCachedLatencies, newLatencyHistogram))
+	}
+	if len(stats.uncachedClientLatencies) > 0 {
+		stats.uncachedClientLatencies.mergeInto(histogramOnFirstUse(&clientSidePerQueryUncachedLatencies[cmdPos], newLatencyHistogram), histogramOnFirstUse(&clientSideAllQueriesUncachedLatencies, newLatencyHistogram))
+		stats.uncachedGraphInternalLatencies.mergeInto(histogramOnFirstUse(&serverSidePerQueryUncachedLatencies[cmdPos], newLatencyHistogram), histogramOnFirstUse(&serverSideAllQueriesUncachedLatencies, newLatencyHistogram))
+	}
+}
+
+// NewPlanCacheStats summarizes the plan cache histograms, nil for the ones that were never created
+func NewPlanCacheStats(cachedExecutions uint64, uncachedClient, uncachedInternal, cachedClient, cachedInternal *hdrhistogram.Histogram) PlanCacheStats {
+	uncachedClient, uncachedInternal = histogramOrEmpty(uncachedClient), histogramOrEmpty(uncachedInternal)
+	cachedClient, cachedInternal = histogramOrEmpty(cachedClient), histogramOrEmpty(cachedInternal)
+	stats := PlanCacheStats{CachedExecutions: cachedExecutions}
+	stats.Executions = uint64(uncachedClient.TotalCount()) + cachedExecutions
+	if stats.Executions > 0 {
+		stats.CachedRatio = float64(cachedExecutions) / float64(stats.Executions)
+	}
+	_, stats.UncachedClientLatencies = generateLatenciesMap(uncachedClient)
+	_, stats.UncachedGraphInternalLatencies = generateLatenciesMap(uncachedInternal)
+	_, stats.CachedClientLatencies = generateLatenciesMap(cachedClient)
+	_, stats.CachedGraphInternalLatencies = generateLatenciesMap(cachedInternal)
+	return stats
+}
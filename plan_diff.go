@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"log"
+	"os"
+	"strings"
+)
+
+const planDiffCommand = "plan-diff"
+
+// runPlanDiff implements the plan-diff subcommand: it compares the execution plans and latencies of the queries of two
+// result files, matched by query id
+func runPlanDiff(args []string) {
+	flags := flag.NewFlagSet(planDiffCommand, flag.ExitOnError)
+	showUnchanged := flags.Bool("show_unchanged", false, "Also print the plans that did not change")
+	failOnChange := flags.Bool("fail_on_change", false, "Exit with status 1 if any execution plan changed")
+	ignoreDataset := flags.Bool("ignore_dataset", false, "Compare results of different datasets")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: falkordb-benchmark-go %s [options] <baseline result file> <comparison result file>\n", planDiffCommand)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	baseline, err := readJsonResult(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read the baseline result file: %v", err)
+	}
+	comparison, err := readJsonResult(flags.Arg(1))
+	if err != nil {
+		log.Fatalf("Failed to read the comparison result file: %v", err)
+	}
+	if same, known := sameDataset(baseline.Dataset, comparison.Dataset); known && !same && !*ignoreDataset {
+		log.Fatalf("The results ran on different datasets, %s and %s. Use --ignore_dataset to compare them anyway", baseline.Dataset, comparison.Dataset)
+	} else if !known {
+		fmt.Println("Warning: unable to tell whether the results ran on the same dataset")
+	}
+	fmt.Printf("Comparing %s ( FalkorDB version %v ) with %s ( FalkorDB version %v )\n", flags.Arg(0), baseline.DBSpecificConfigs["FalkorDBVersion"], flags.Arg(1), comparison.DBSpecificConfigs["FalkorDBVersion"])
+
+	baselineQueries := map[string]QueryStats{}
+	for _, query := range baseline.Queries {
+		baselineQueries[query.Id] = query
+	}
+	changedPlans := 0
+	data := make([][]string, 0)
+	for _, query := range comparison.Queries {
+		baselineQuery, ok := baselineQueries[query.Id]
+		if !ok {
+			data = append(data, []string{query.Id, "new query", "", fmt.Sprintf("%.3f", query.ClientLatencies["q50"]), "", "", fmt.Sprintf("%.3f", query.GraphInternalLatencies["q50"]), ""})
+			continue
+		}
+		delete(baselineQueries, query.Id)
+		planStatus := "unchanged"
+		diff := diffLines(baselineQuery.ExecutionPlan.Explain, query.ExecutionPlan.Explain)
+		changed := linesChanged(diff)
+		if len(baselineQuery.ExecutionPlan.Explain) == 0 || len(query.ExecutionPlan.Explain) == 0 {
+			planStatus = "not captured"
+			changed = false
+		} else if changed {
+			planStatus = "changed"
+			changedPlans++
+		}
+		data = append(data, []string{query.Id, planStatus,
+			fmt.Sprintf("%.3f", baselineQuery.ClientLatencies["q50"]), fmt.Sprintf("%.3f", query.ClientLatencies["q50"]), percentChange(baselineQuery.ClientLatencies["q50"], query.ClientLatencies["q50"]),
+			fmt.Sprintf("%.3f", baselineQuery.GraphInternalLatencies["q50"]), fmt.Sprintf("%.3f", query.GraphInternalLatencies["q50"]), percentChange(baselineQuery.GraphInternalLatencies["q50"], query.GraphInternalLatencies["q50"])})
+		if changed || (*showUnchanged && planStatus == "unchanged") {
+			fmt.Printf("\n## Query %s, execution plan %s\n", query.Id, planStatus)
+			fmt.Printf("%s\n", query.Query)
+			fmt.Printf("Client p50 latency: %.3f ms -> %.3f ms ( %s )\n", baselineQuery.ClientLatencies["q50"], query.ClientLatencies["q50"], percentChange(baselineQuery.ClientLatencies["q50"], query.ClientLatencies["q50"]))
+			fmt.Printf("Graph internal p50 latency: %.3f ms -> %.3f ms ( %s )\n", baselineQuery.GraphInternalLatencies["q50"], query.GraphInternalLatencies["q50"], percentChange(baselineQuery.GraphInternalLatencies["q50"], query.GraphInternalLatencies["q50"]))
+			fmt.Printf("%s\n", strings.Join(diff, "\n"))
+		}
+	}
+	for _, query := range baseline.Queries {
+		if _, removed := baselineQueries[query.Id]; removed {
+			data = append(data, []string{query.Id, "removed query", fmt.Sprintf("%.3f", query.ClientLatencies["q50"]), "", "", fmt.Sprintf("%.3f", query.GraphInternalLatencies["q50"]), "", ""})
+		}
+	}
+
+	fmt.Printf("\n## Execution plan diff summary table\n")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Query", "Execution plan", "Baseline client p50(ms)", "Client p50(ms)", "Client p50 change", "Baseline internal p50(ms)", "Internal p50(ms)", "Internal p50 change"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+	fmt.Printf("%d execution plan(s) changed\n", changedPlans)
+	if *failOnChange && changedPlans > 0 {
+		os.Exit(1)
+	}
+}
+
+func readJsonResult(jsonResultFile string) (testResult TestResult, err error) {
+	file, err := os.ReadFile(jsonResultFile)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(file, &testResult)
+	if err == nil && testResult.ResultFormatVersion != resultFormatVersion {
+		err = fmt.Errorf("%s has result format version %s, expected %s", jsonResultFile, testResult.ResultFormatVersion, resultFormatVersion)
+	}
+	return
+}
+
+// diffLines returns a line diff of the two plans, prefixing each line with "  " ( unchanged ), "- " ( removed ) or
+// "+ " ( added ). Trailing whitespace is ignored.
+func diffLines(baseline, comparison []string) []string {
+	a := trimLines(baseline)
+	b := trimLines(comparison)
+	// longest common subsequence table, lcs[i][j] being the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	diff := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, "+ "+b[j])
+	}
+	return diff
+}
+
+func trimLines(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimRight(line, " \t")
+	}
+	return trimmed
+}
+
+func linesChanged(diff []string) bool {
+	for _, line := range diff {
+		if !strings.HasPrefix(line, "  ") {
+			return true
+		}
+	}
+	return false
+}
+
+func percentChange(baseline, comparison float64) string {
+	if baseline == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", (comparison-baseline)/baseline*100.0)
+}
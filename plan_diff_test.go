@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_diffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want []string
+	}{
+		{"unchanged", []string{"Results", "    Project"}, []string{"Results", "    Project "}, []string{"  Results", "      Project"}},
+		{"operator-replaced", []string{"Results", "    Filter", "        Node By Label Scan | (n:N)"}, []string{"Results", "    Node By Index Scan | (n:N)"},
+			[]string{"  Results", "-     Filter", "-         Node By Label Scan | (n:N)", "+     Node By Index Scan | (n:N)"}},
+		{"operator-added", []string{"Results", "    Project"}, []string{"Results", "    Sort", "    Project"}, []string{"  Results", "+     Sort", "      Project"}},
+		{"empty-baseline", []string{}, []string{"Results"}, []string{"+ Results"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_percentChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseline   float64
+		comparison float64
+		want       string
+	}{
+		{"slower", 0.4, 0.5, "+25.0%"},
+		{"faster", 0.5, 0.4, "-20.0%"},
+		{"no-baseline", 0, 0.4, "n/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentChange(tt.baseline, tt.comparison); got != tt.want {
+				t.Errorf("percentChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// procClockTicks is the USER_HZ unit of the /proc/<pid>/stat cpu times. It is 100 on every mainstream Linux build.
+const procClockTicks = 100.0
+
+// databaseContainerName is the name given to the container started by RunFalkorDBContainers
+const databaseContainerName = "falkordb"
+
+// ProcessStatsSample holds the resource usage of the database process at a point in time
+type ProcessStatsSample struct {
+	TimestampMillis int64 `json:"TimestampMillis"`
+	// Cumulative user and system cpu time. In container mode it is integrated from the cpu percentage
+	CpuSeconds float64 `json:"CpuSeconds"`
+	// Cpu usage since the previous sample, 100 being one full core
+	CpuPercent                  float64 `json:"CpuPercent"`
+	RssBytes                    int64   `json:"RssBytes"`
+	Threads                     int64   `json:"Threads"`
+	VoluntaryContextSwitches    int64   `json:"VoluntaryContextSwitches"`
+	NonVoluntaryContextSwitches int64   `json:"NonVoluntaryContextSwitches"`
+	ReadBytes                   int64   `json:"ReadBytes"`
+	WriteBytes                  int64   `json:"WriteBytes"`
+}
+
+// ProcessStatsSummary aggregates the database process samples of the measured phase
+type ProcessStatsSummary struct {
+	// proc or docker
+	Source                 string               `json:"Source"`
+	PeakCpuPercent         float64              `json:"PeakCpuPercent"`
+	AvgCpuPercent          float64              `json:"AvgCpuPercent"`
+	PeakRssBytes           int64                `json:"PeakRssBytes"`
+	AvgRssBytes            float64              `json:"AvgRssBytes"`
+	PeakThreads            int64                `json:"PeakThreads"`
+	AvgThreads             float64              `json:"AvgThreads"`
+	CpuSeconds             float64              `json:"CpuSeconds"`
+	CpuSecondsPer1kQueries float64              `json:"CpuSecondsPer1kQueries"`
+	ContextSwitches        int64                `json:"ContextSwitches"`
+	ReadBytes              int64                `json:"ReadBytes"`
+	WriteBytes             int64                `json:"WriteBytes"`
+	Samples                []ProcessStatsSample `json:"Samples"`
+}
+
+// processStatsSampler samples the database process from /proc/<pid>, or the database container through docker stats,
+// on its own go-routine given docker stats takes a while to reply
+type processStatsSampler struct {
+	pid      int
+	isDocker bool
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	// set once sampling failed, e.g. on a non Linux host
+	failed  bool
+	mutex   sync.Mutex
+	samples []ProcessStatsSample
+}
+
+func startProcessStatsSampler(cmd *exec.Cmd, isDocker bool, interval time.Duration) *processStatsSampler {
+	s := &processStatsSampler{pid: cmd.Process.Pid, isDocker: isDocker, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+func (s *processStatsSampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.sample(); err != nil {
+			fmt.Printf("\nUnable to sample the database process resources, no longer sampling them. Error: %v\n", err)
+			s.failed = true
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *processStatsSampler) sample() error {
+	now := time.Now()
+	var sample ProcessStatsSample
+	var err error
+	if s.isDocker {
+		sample, err = sampleDockerStats(databaseContainerName)
+	} else {
+		sample, err = sampleProcStats(s.pid)
+	}
+	if err != nil {
+		return err
+	}
+	sample.TimestampMillis = now.UnixMilli()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.samples) > 0 {
+		previous := s.samples[len(s.samples)-1]
+		elapsed := float64(sample.TimestampMillis-previous.TimestampMillis) / 1000.0
+		if s.isDocker {
+			// docker stats reports the cpu percentage, the cpu time is integrated from it
+			sample.CpuSeconds = previous.CpuSeconds + sample.CpuPercent/100.0*elapsed
+		} else if elapsed > 0 {
+			sample.CpuPercent = (sample.CpuSeconds - previous.CpuSeconds) / elapsed * 100.0
+		}
+	}
+	s.samples = append(s.samples, sample)
+	return nil
+}
+
+// Stop takes a last sample and summarizes the samples, given the number of queries issued meanwhile
+func (s *processStatsSampler) Stop(issuedQueries uint64) *ProcessStatsSummary {
+	close(s.stop)
+	<-s.done
+	if !s.failed {
+		s.sample()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.samples) < 2 {
+		return nil
+	}
+	source := "proc"
+	if s.isDocker {
+		source = "docker"
+	}
+	return NewProcessStatsSummary(source, s.samples, issuedQueries)
+}
+
+func NewProcessStatsSummary(source string, samples []ProcessStatsSample, issuedQueries uint64) *ProcessStatsSummary {
+	summary := &ProcessStatsSummary{Source: source, Samples: samples}
+	first, last := samples[0], samples[len(samples)-1]
+	// the first sample is only the baseline of the cpu percentage
+	for _, sample := range samples[1:] {
+		if sample.CpuPercent > summary.PeakCpuPercent {
+			summary.PeakCpuPercent = sample.CpuPercent
+		}
+		if sample.RssBytes > summary.PeakRssBytes {
+			summary.PeakRssBytes = sample.RssBytes
+		}
+		if sample.Threads > summary.PeakThreads {
+			summary.PeakThreads = sample.Threads
+		}
+		summary.AvgCpuPercent += sample.CpuPercent
+		summary.AvgRssBytes += float64(sample.RssBytes)
+		summary.AvgThreads += float64(sample.Threads)
+	}
+	count := float64(len(samples) - 1)
+	summary.AvgCpuPercent /= count
+	summary.AvgRssBytes /= count
+	summary.AvgThreads /= count
+	summary.CpuSeconds = last.CpuSeconds - first.CpuSeconds
+	if issuedQueries > 0 {
+		summary.CpuSecondsPer1kQueries = summary.CpuSeconds / float64(issuedQueries) * 1000.0
+	}
+	summary.ContextSwitches = last.VoluntaryContextSwitches + last.NonVoluntaryContextSwitches - first.VoluntaryContextSwitches - first.NonVoluntaryContextSwitches
+	summary.ReadBytes = last.ReadBytes - first.ReadBytes
+	summary.WriteBytes = last.WriteBytes - first.WriteBytes
+	return summary
+}
+
+func sampleProcStats(pid int) (sample ProcessStatsSample, err error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return
+	}
+	sample.CpuSeconds, sample.Threads, err = parseProcStat(string(stat))
+	if err != nil {
+		return
+	}
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return
+	}
+	fields := parseProcFields(string(status))
+	sample.RssBytes = procFieldInt(fields, "VmRSS") * 1024
+	sample.VoluntaryContextSwitches = procFieldInt(fields, "voluntary_ctxt_switches")
+	sample.NonVoluntaryContextSwitches = procFieldInt(fields, "nonvoluntary_ctxt_switches")
+	// io accounting might not be readable, it's left empty then
+	if io, ioErr := os.ReadFile(fmt.Sprintf("/proc/%d/io", pid)); ioErr == nil {
+		fields = parseProcFields(string(io))
+		sample.ReadBytes = procFieldInt(fields, "read_bytes")
+		sample.WriteBytes = procFieldInt(fields, "write_bytes")
+	}
+	return
+}
+
+// parseProcStat returns the user plus system cpu time and the number of threads out of /proc/<pid>/stat
+func parseProcStat(stat string) (cpuSeconds float64, threads int64, err error) {
+	// the command name is between parenthesis and might contain spaces
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		err = fmt.Errorf("unexpected /proc stat format: %s", stat)
+		return
+	}
+	// fields after the command name, starting with the state ( field 3 )
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 18 {
+		err = fmt.Errorf("unexpected /proc stat format: %s", stat)
+		return
+	}
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	threads, _ = strconv.ParseInt(fields[17], 10, 64)
+	cpuSeconds = (utime + stime) / procClockTicks
+	return
+}
+
+// parseProcFields parses the "key: value" lines of /proc/<pid>/status and /proc/<pid>/io
+func parseProcFields(content string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		if key, value, found := strings.Cut(line, ":"); found {
+			fields[key] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB"))
+		}
+	}
+	return fields
+}
+
+func procFieldInt(fields map[string]string, key string) int64 {
+	value, _ := strconv.ParseInt(fields[key], 10, 64)
+	return value
+}
+
+// dockerStats holds the fields of docker stats --format '{{json .}}' that are used
+type dockerStats struct {
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	PIDs     string `json:"PIDs"`
+	BlockIO  string `json:"BlockIO"`
+}
+
+func sampleDockerStats(container string) (sample ProcessStatsSample, err error) {
+	output, err := exec.Command("docker", "stats", "--no-stream", "--format", "{{json .}}", container).Output()
+	if err != nil {
+		return
+	}
+	return parseDockerStats(output)
+}
+
+func parseDockerStats(output []byte) (sample ProcessStatsSample, err error) {
+	var stats dockerStats
+	if err = json.Unmarshal(output, &stats); err != nil {
+		return
+	}
+	sample.CpuPercent, _ = strconv.ParseFloat(strings.TrimSuffix(stats.CPUPerc, "%"), 64)
+	memUsage, _, _ := strings.Cut(stats.MemUsage, "/")
+	sample.RssBytes = parseDockerSize(memUsage)
+	sample.Threads, _ = strconv.ParseInt(strings.TrimSpace(stats.PIDs), 10, 64)
+	read, write, _ := strings.Cut(stats.BlockIO, "/")
+	sample.ReadBytes = parseDockerSize(read)
+	sample.WriteBytes = parseDockerSize(write)
+	return
+}
+
+// parseDockerSize parses the human readable sizes of docker stats, e.g. 16.5MiB or 1.2kB
+func parseDockerSize(size string) int64 {
+	size = strings.TrimSpace(size)
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12}, {"B", 1},
+	}
+	for _, unit := range units {
+		if strings.HasSuffix(size, unit.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(size, unit.suffix), 64)
+			if err != nil {
+				return 0
+			}
+			return int64(value * unit.multiplier)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_parseProcStat(t *testing.T) {
+	stat := "4242 (redis server) S 1 4242 4242 0 -1 4194560 1210 0 0 0 250 130 0 0 20 0 7 0 247308 2703360 286 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0"
+	cpuSeconds, threads, err := parseProcStat(stat)
+	if err != nil {
+		t.Fatalf("parseProcStat() error = %v", err)
+	}
+	if cpuSeconds != 3.8 || threads != 7 {
+		t.Errorf("parseProcStat() = %v, %v, want %v, %v", cpuSeconds, threads, 3.8, 7)
+	}
+	if _, _, err = parseProcStat("garbage"); err == nil {
+		t.Errorf("parseProcStat() expected an error on malformed input")
+	}
+}
+
+func Test_parseProcFields(t *testing.T) {
+	fields := parseProcFields("Name:\tredis-server\nVmRSS:\t    1828 kB\nvoluntary_ctxt_switches:\t12\n")
+	if got := procFieldInt(fields, "VmRSS"); got != 1828 {
+		t.Errorf("VmRSS = %v, want %v", got, 1828)
+	}
+	if got := procFieldInt(fields, "voluntary_ctxt_switches"); got != 12 {
+		t.Errorf("voluntary_ctxt_switches = %v, want %v", got, 12)
+	}
+}
+
+func Test_parseDockerSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want int64
+	}{
+		{"0B", 0},
+		{"512B", 512},
+		{"1.5kB", 1500},
+		{"16.5MiB", 17301504},
+		{" 2GiB ", 2147483648},
+		{"n/a", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			if got := parseDockerSize(tt.size); got != tt.want {
+				t.Errorf("parseDockerSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseDockerStats(t *testing.T) {
+	sample, err := parseDockerStats([]byte(`{"BlockIO":"1.2MB / 0B","CPUPerc":"12.50%","Container":"falkordb","MemUsage":"16MiB / 7.6GiB","Name":"falkordb","PIDs":"9"}`))
+	if err != nil {
+		t.Fatalf("parseDockerStats() error = %v", err)
+	}
+	want := ProcessStatsSample{CpuPercent: 12.5, RssBytes: 16 << 20, Threads: 9, ReadBytes: 1200000}
+	if sample != want {
+		t.Errorf("parseDockerStats() = %+v, want %+v", sample, want)
+	}
+}
+
+func Test_NewProcessStatsSummary(t *testing.T) {
+	samples := []ProcessStatsSample{
+		{TimestampMillis: 0, CpuSeconds: 1, RssBytes: 100, Threads: 4},
+		{TimestampMillis: 1000, CpuSeconds: 1.5, CpuPercent: 50, RssBytes: 300, Threads: 8, VoluntaryContextSwitches: 10},
+		{TimestampMillis: 2000, CpuSeconds: 2.5, CpuPercent: 100, RssBytes: 200, Threads: 6, VoluntaryContextSwitches: 15, NonVoluntaryContextSwitches: 5},
+	}
+	summary := NewProcessStatsSummary("proc", samples, 3000)
+	if summary.PeakCpuPercent != 100 || summary.AvgCpuPercent != 75 {
+		t.Errorf("cpu percent peak/avg = %v/%v, want 100/75", summary.PeakCpuPercent, summary.AvgCpuPercent)
+	}
+	if summary.PeakRssBytes != 300 || summary.AvgRssBytes != 250 {
+		t.Errorf("rss peak/avg = %v/%v, want 300/250", summary.PeakRssBytes, summary.AvgRssBytes)
+	}
+	if summary.CpuSeconds != 1.5 || summary.CpuSecondsPer1kQueries != 0.5 {
+		t.Errorf("cpu seconds = %v ( %v per 1k ), want 1.5 ( 0.5 per 1k )", summary.CpuSeconds, summary.CpuSecondsPer1kQueries)
+	}
+	if summary.ContextSwitches != 20 {
+		t.Errorf("context switches = %v, want 20", summary.ContextSwitches)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// queryTemplate is a query parsed once at startup into literal segments and placeholder slots, so that rendering a
+// request does not scan nor rewrite the query
+type queryTemplate struct {
+	query string
+	// the query starts with a CYPHER params header, the term source params are added to it
+	cypherHeader bool
+	// literals has one more entry than slots: literals[i] precedes slots[i], and the last literal ends the query.
+	// The CYPHER keyword of the header is not part of them.
+	literals []string
+	slots    []templateSlot
+	// size of the literals, used to size the rendered query
+	literalsSize int
+}
+
+type templateSlot struct {
+	randInt bool
+	// the replacement term placeholder, for non randInt slots
+	term string
+}
+
+// newQueryTemplate parses the query given the replacement term placeholders ( the term sources columns ).
+// The leftmost placeholder wins, and the longest one on ties.
+func newQueryTemplate(query string, termPlaceholders []string) *queryTemplate {
+	placeholders := append([]string{randIntPlaceholder}, termPlaceholders...)
+	sort.SliceStable(placeholders, func(i, j int) bool {
+		return len(placeholders[i]) > len(placeholders[j])
+	})
+	template := &queryTemplate{query: query}
+	body := strings.TrimLeftFunc(query, unicode.IsSpace)
+	if len(body) > len("CYPHER") && strings.EqualFold(body[:len("CYPHER")], "CYPHER") && unicode.IsSpace(rune(body[len("CYPHER")])) {
+		template.cypherHeader = true
+		body = body[len("CYPHER"):]
+	} else {
+		body = query
+	}
+	for {
+		pos, placeholder := -1, ""
+		for _, candidate := range placeholders {
+			if candidate == "" {
+				continue
+			}
+			if i := strings.Index(body, candidate); i != -1 && (pos == -1 || i < pos) {
+				pos, placeholder = i, candidate
+			}
+		}
+		if pos == -1 {
+			break
+		}
+		template.literals = append(template.literals, body[:pos])
+		template.literalsSize += pos
+		template.slots = append(template.slots, templateSlot{randInt: placeholder == randIntPlaceholder, term: placeholder})
+		body = body[pos+len(placeholder):]
+	}
+	template.literals = append(template.literals, body)
+	template.literalsSize += len(body)
+	return template
+}
+
+func newQueryTemplates(queries []string, queryPlaceholders [][]string) []*queryTemplate {
+	templates := make([]*queryTemplate, len(queries))
+	for i, query := range queries {
+		templates[i] = newQueryTemplate(query, queryPlaceholders[i])
+	}
+	return templates
+}
+
+// render replaces every __rand_int__ slot with a new random integer, and every term slot with its term from the lines
+// picked for the request. Term slots without a term are left as is. The lines params are added to the CYPHER header.
+func (t *queryTemplate) render(randomIntPadding int64, randomIntMax int64, termRows []*termRow) string {
+	paramsSize := 0
+	for _, row := range termRows {
+		paramsSize += len(row.params)
+	}
+	if len(t.slots) == 0 && paramsSize == 0 {
+		return t.query
+	}
+	var builder strings.Builder
+	// random integers are at most 20 bytes long, terms are usually short
+	builder.Grow(len("CYPHER ") + paramsSize + t.literalsSize + 20*len(t.slots))
+	if t.cypherHeader || paramsSize > 0 {
+		builder.WriteString("CYPHER")
+		for _, row := range termRows {
+			builder.WriteString(row.params)
+		}
+		if !t.cypherHeader {
+			builder.WriteByte(' ')
+		}
+	}
+	var intBuf [20]byte
+	for i, slot := range t.slots {
+		builder.WriteString(t.literals[i])
+		if slot.randInt {
+			builder.Write(strconv.AppendInt(intBuf[:0], rand.Int63n(randomIntMax)+randomIntPadding, 10))
+		} else {
+			builder.WriteString(templateTerm(slot.term, termRows))
+		}
+	}
+	builder.WriteString(t.literals[len(t.slots)])
+	return builder.String()
+}
+
+func templateTerm(placeholder string, termRows []*termRow) string {
+	for _, row := range termRows {
+		if term, ok := row.terms[placeholder]; ok {
+			return term
+		}
+	}
+	return placeholder
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+)
+
+// workerReadTimeoutSecs is the minimum read timeout of the worker connections
+const workerReadTimeoutSecs = 5
+
+// queryTimeouts holds the per query timeout settings
+type queryTimeouts struct {
+	// passed to FalkorDB as the query timeout argument, in milliseconds. 0 means the server default
+	server int
+	// client side deadline of every attempt. 0 means no deadline
+	client time.Duration
+}
+
+func newQueryTimeouts(queries []Query) []queryTimeouts {
+	timeouts := make([]queryTimeouts, len(queries))
+	for i, query := range queries {
+		timeouts[i].server = query.Timeout
+		timeouts[i].client = time.Duration(query.ClientTimeout) * time.Millisecond
+	}
+	return timeouts
+}
+
+// workerReadTimeout returns the read timeout of the worker connections, in seconds. It is large enough to never cut
+// a query before its own server or client timeout kicks in.
+func workerReadTimeout(timeouts []queryTimeouts) int {
+	readTimeout := workerReadTimeoutSecs
+	for _, timeout := range timeouts {
+		longest := time.Duration(timeout.server) * time.Millisecond
+		if timeout.client > longest {
+			longest = timeout.client
+		}
+		// one extra second for the reply to make it back
+		secs := int((longest+time.Second-1)/time.Second) + 1
+		if longest > 0 && secs > readTimeout {
+			readTimeout = secs
+		}
+	}
+	return readTimeout
+}
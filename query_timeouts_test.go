@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_workerReadTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts []queryTimeouts
+		want     int
+	}{
+		{"no-timeouts", []queryTimeouts{{}, {}}, 5},
+		{"short-timeouts", []queryTimeouts{{server: 1000}, {client: 2 * time.Second}}, 5},
+		{"long-server-timeout", []queryTimeouts{{server: 10000}, {}}, 11},
+		{"long-client-timeout", []queryTimeouts{{server: 1000, client: 12500 * time.Millisecond}}, 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workerReadTimeout(tt.timeouts); got != tt.want {
+				t.Errorf("workerReadTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"strconv"
+)
+
+// replySize approximates the size in bytes of a reply, as encoded in RESP
+func replySize(reply interface{}) uint64 {
+	switch v := reply.(type) {
+	case nil:
+		return 5
+	case string:
+		return uint64(len(v)+len(strconv.Itoa(len(v)))) + 5
+	case []byte:
+		return uint64(len(v)+len(strconv.Itoa(len(v)))) + 5
+	case int64:
+		return uint64(len(strconv.FormatInt(v, 10))) + 3
+	case bool:
+		return 4
+	case float64:
+		return uint64(len(strconv.FormatFloat(v, 'g', -1, 64))) + 3
+	case []interface{}:
+		size := uint64(len(strconv.Itoa(len(v)))) + 3
+		for _, element := range v {
+			size += replySize(element)
+		}
+		return size
+	case map[interface{}]interface{}:
+		size := uint64(len(strconv.Itoa(len(v)))) + 3
+		for key, value := range v {
+			size += replySize(key) + replySize(value)
+		}
+		return size
+	default:
+		return uint64(len(fmt.Sprint(v))) + 3
+	}
+}
+
+// recordResultSize accounts the rows and reply size of the successful requests of a worker interval
+func recordResultSize(cmdPos int, stats *workerQueryStats) {
+	totalRowsPerQuery[cmdPos] += stats.rows
+	totalReplyBytesPerQuery[cmdPos] += stats.replyBytes
+	totalEmptyResultsets += stats.emptyResultsets
+	emptyResultsetsPerQuery[cmdPos] += stats.emptyResultsets
+	stats.rowsPerRequest.mergeInto(rowsPerRequestPerQuery[cmdPos], rowsPerRequestAllQueries)
+	stats.replyBytesPerRequest.mergeInto(replyBytesPerRequestPerQuery[cmdPos], replyBytesPerRequestAllQueries)
+}
+
+// ResultSizeStats holds the distribution of the rows and of the approximate reply size of each request
+type ResultSizeStats struct {
+	Rows       map[string]float64 `json:"Rows"`
+	ReplyBytes map[string]float64 `json:"ReplyBytes"`
+}
+
+func NewResultSizeStats(rows *hdrhistogram.Histogram, replyBytes *hdrhistogram.Histogram) ResultSizeStats {
+	_, rowsMap := generateQuantilesMap(rows, 1.0)
+	_, replyBytesMap := generateQuantilesMap(replyBytes, 1.0)
+	return ResultSizeStats{Rows: rowsMap, ReplyBytes: replyBytesMap}
+}
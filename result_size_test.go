@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func Test_replySize(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply interface{}
+		want  uint64
+	}{
+		{"nil", nil, 5},
+		{"string", "OK", 8},
+		{"integer", int64(1234), 7},
+		{"array", []interface{}{int64(1), "a"}, 4 + 4 + 7},
+		{"nested-array", []interface{}{[]interface{}{}}, 4 + 4},
+		{"map", map[interface{}]interface{}{"k": int64(1)}, 4 + 7 + 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replySize(tt.reply); got != tt.want {
+				t.Errorf("replySize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"math/rand"
+	"reflect"
+	"slices"
+)
+
+// ValidationStats holds the outcome of the result set validation of a query
+type ValidationStats struct {
+	// Number of results validated, and how many of them did not match the expectations
+	Checked    uint64 `json:"Checked"`
+	Violations uint64 `json:"Violations"`
+	// The first distinct violations
+	Samples []string `json:"Samples"`
+}
+
+// resultValidator checks the result sets of a query against its expectations
+type resultValidator struct {
+	expect     *QueryExpectations
+	sampleRate float64
+}
+
+// newResultValidators returns a validator per query, nil for the queries without expectations
+func newResultValidators(queries []Query) []*resultValidator {
+	validators := make([]*resultValidator, len(queries))
+	for i, query := range queries {
+		if query.Expect == nil {
+			continue
+		}
+		sampleRate := query.Expect.SampleRate
+		if sampleRate == 0 {
+			sampleRate = 1.0
+		}
+		validators[i] = &resultValidator{expect: query.Expect, sampleRate: sampleRate}
+	}
+	return validators
+}
+
+// sampled decides whether the current result is validated
+func (v *resultValidator) sampled() bool {
+	return v.sampleRate >= 1.0 || rand.Float64() < v.sampleRate
+}
+
+// validate returns a description of the first expectation the result set does not meet, or an empty string
+func (v *resultValidator) validate(columns []string, rows [][]interface{}, replacementTerms map[string]string) string {
+	expect := v.expect
+	rowCount := len(rows)
+	if expect.Rows != nil && rowCount != *expect.Rows {
+		return fmt.Sprintf("expected %d rows, got %d", *expect.Rows, rowCount)
+	}
+	if expect.MinRows != nil && rowCount < *expect.MinRows {
+		return fmt.Sprintf("expected at least %d rows, got %d", *expect.MinRows, rowCount)
+	}
+	if expect.MaxRows != nil && rowCount > *expect.MaxRows {
+		return fmt.Sprintf("expected at most %d rows, got %d", *expect.MaxRows, rowCount)
+	}
+	if expect.NonEmpty && rowCount == 0 {
+		return "expected a non empty result"
+	}
+	// column names are only known when at least one record was returned
+	if len(expect.Columns) > 0 && rowCount > 0 && !reflect.DeepEqual(columns, expect.Columns) {
+		return fmt.Sprintf("expected columns %v, got %v", expect.Columns, columns)
+	}
+	for _, values := range expect.Values {
+		if !termsMatch(values.When, replacementTerms) {
+			continue
+		}
+		if rowCount == 0 {
+			return fmt.Sprintf("expected first row %v for %v, got an empty result", values.FirstRow, values.When)
+		}
+		firstRow := make([]string, len(rows[0]))
+		for i, value := range rows[0] {
+			firstRow[i] = fmt.Sprint(value)
+		}
+		if !reflect.DeepEqual(firstRow, values.FirstRow) {
+			return fmt.Sprintf("expected first row %v for %v, got %v", values.FirstRow, values.When, firstRow)
+		}
+	}
+	return ""
+}
+
+// termsMatch returns true if every placeholder of when was replaced by the given term
+func termsMatch(when map[string]string, replacementTerms map[string]string) bool {
+	for placeholder, term := range when {
+		if replacementTerms[placeholder] != term {
+			return false
+		}
+	}
+	return true
+}
+
+// resultSetRows reads the column names and the values of every record of the result
+func resultSetRows(queryResult *falkordb.QueryResult) (columns []string, rows [][]interface{}) {
+	for queryResult.Next() {
+		record := queryResult.Record()
+		columns = record.Keys()
+		rows = append(rows, record.Values())
+	}
+	return
+}
+
+// resultSetSize counts the records of the result, without reading them
+func resultSetSize(queryResult *falkordb.QueryResult) (size uint64) {
+	for queryResult.Next() {
+		size++
+	}
+	return
+}
+
+// recordValidation accounts the validation outcome of the successful requests of a worker interval
+func recordValidation(cmdPos int, interval *workerQueryStats) {
+	for _, stats := range []*ValidationStats{validationStatsPerQuery[cmdPos], totalValidationStats} {
+		stats.Checked += interval.validated
+		for _, violation := range interval.violations {
+			stats.Violations++
+			if len(stats.Samples) < maxErrorSamples && !slices.Contains(stats.Samples, violation) {
+				stats.Samples = append(stats.Samples, violation)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/FalkorDB/falkordb-go"
+	"testing"
+)
+
+func Test_resultValidator_validate(t *testing.T) {
+	one, two := 1, 2
+	type args struct {
+		columns          []string
+		rows             [][]interface{}
+		replacementTerms map[string]string
+	}
+	tests := []struct {
+		name   string
+		expect QueryExpectations
+		args   args
+		want   string
+	}{
+		{"exact-rows", QueryExpectations{Rows: &one}, args{[]string{"n"}, [][]interface{}{{1}}, nil}, ""},
+		{"exact-rows-mismatch", QueryExpectations{Rows: &one}, args{[]string{"n"}, [][]interface{}{{1}, {2}}, nil}, "expected 1 rows, got 2"},
+		{"min-rows", QueryExpectations{MinRows: &two}, args{[]string{"n"}, [][]interface{}{{1}}, nil}, "expected at least 2 rows, got 1"},
+		{"max-rows", QueryExpectations{MaxRows: &one}, args{[]string{"n"}, [][]interface{}{{1}, {2}}, nil}, "expected at most 1 rows, got 2"},
+		{"non-empty", QueryExpectations{NonEmpty: true}, args{nil, nil, nil}, "expected a non empty result"},
+		{"columns", QueryExpectations{Columns: []string{"id", "count"}}, args{[]string{"id", "total"}, [][]interface{}{{1, 2}}, nil}, "expected columns [id count], got [id total]"},
+		{"columns-empty-result", QueryExpectations{Columns: []string{"id"}}, args{nil, nil, nil}, ""},
+		{"values", QueryExpectations{Values: []ExpectedValues{{When: map[string]string{"__id__": "5"}, FirstRow: []string{"5", "3"}}}}, args{[]string{"id", "count"}, [][]interface{}{{int64(5), int64(3)}}, map[string]string{"__id__": "5"}}, ""},
+		{"values-mismatch", QueryExpectations{Values: []ExpectedValues{{When: map[string]string{"__id__": "5"}, FirstRow: []string{"5", "3"}}}}, args{[]string{"id", "count"}, [][]interface{}{{int64(5), int64(4)}}, map[string]string{"__id__": "5"}}, "expected first row [5 3] for map[__id__:5], got [5 4]"},
+		{"values-other-terms", QueryExpectations{Values: []ExpectedValues{{When: map[string]string{"__id__": "5"}, FirstRow: []string{"5", "3"}}}}, args{[]string{"id", "count"}, [][]interface{}{{int64(6), int64(4)}}, map[string]string{"__id__": "6"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &resultValidator{expect: &tt.expect, sampleRate: 1.0}
+			if got := v.validate(tt.args.columns, tt.args.rows, tt.args.replacementTerms); got != tt.want {
+				t.Errorf("validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resultSetSize(t *testing.T) {
+	header := []interface{}{[]interface{}{int64(1), "n"}}
+	records := []interface{}{[]interface{}{[]interface{}{int64(3), int64(1)}}, []interface{}{[]interface{}{int64(3), int64(2)}}}
+	reply := []interface{}{header, records, []interface{}{"Query internal execution time: 0.1 milliseconds"}}
+	queryResult, err := falkordb.QueryResultNew(&falkordb.Graph{}, reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resultSetSize(queryResult); got != 2 {
+		t.Errorf("resultSetSize() = %v, want 2", got)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+const (
+	stopReasonCompleted      = "completed"
+	stopReasonInterrupted    = "interrupted"
+	stopReasonDatabaseExited = "database process exited"
+)
+
+// runStopper coordinates every path that stops a benchmark run ( completion, signal, error budget, worker error,
+// database crash ). Only the first stop reason is kept.
+type runStopper struct {
+	once   sync.Once
+	reason string
+	done   chan struct{}
+}
+
+func newRunStopper() *runStopper {
+	return &runStopper{done: make(chan struct{})}
+}
+
+func (s *runStopper) Stop(reason string) {
+	s.once.Do(func() {
+		s.reason = reason
+		close(s.done)
+	})
+}
+
+// Done is closed once the run was asked to stop
+func (s *runStopper) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *runStopper) Stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reason must only be called after Done was closed
+func (s *runStopper) Reason() string {
+	return s.reason
+}
+
+// monitorDatabase stops the run if the database process ( or container ) we started exits. It is the only one to
+// reap the process: the returned channel is closed once it did.
+func monitorDatabase(cmd *exec.Cmd, stopper *runStopper) <-chan struct{} {
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		err := cmd.Wait()
+		if !stopper.Stopped() {
+			fmt.Printf("\nDatabase process exited: %v\n", err)
+		}
+		stopper.Stop(stopReasonDatabaseExited)
+	}()
+	return exited
+}
+
+// watchSignals stops the run on a signal received before the measured phase, e.g. while a dataset is loaded, when
+// nothing else reads the signals yet. The returned function stops watching them.
+func watchSignals(c <-chan os.Signal, stopper *runStopper) (unwatch func()) {
+	done, watching := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watching)
+		select {
+		case sig := <-c:
+			fmt.Printf("\nReceived %v - stopping\n", sig)
+			stopper.Stop(stopReasonInterrupted)
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-watching
+	}
+}
+
+// escalateSignals handles the signals received once the run is stopping: the signal handler stays installed until
+// the database is stopped, and a repeated C-c stops the database and exits right away instead of killing the process
+// without any cleanup. When the run stopped on its own, the first signal is the interrupt and the next one escalates.
+func escalateSignals(c <-chan os.Signal, stopper *runStopper, stopDatabase func()) {
+	interrupted := stopper.Reason() == stopReasonInterrupted
+	go func() {
+		for sig := range c {
+			if !interrupted {
+				fmt.Printf("\nReceived %v - waiting for the in-flight requests, send it again to exit right away\n", sig)
+				interrupted = true
+				continue
+			}
+			fmt.Printf("\nReceived %v while stopping - stopping the database and exiting without the results\n", sig)
+			stopDatabase()
+			os.Exit(1)
+		}
+	}()
+}
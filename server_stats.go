@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerStatsSample holds the server INFO fields, the graph memory usage and the graph size at a point in time
+type ServerStatsSample struct {
+	TimestampMillis        int64   `json:"TimestampMillis"`
+	UsedMemory             int64   `json:"UsedMemory"`
+	UsedMemoryRss          int64   `json:"UsedMemoryRss"`
+	UsedCpuSys             float64 `json:"UsedCpuSys"`
+	UsedCpuUser            float64 `json:"UsedCpuUser"`
+	ConnectedClients       int64   `json:"ConnectedClients"`
+	InstantaneousOpsPerSec int64   `json:"InstantaneousOpsPerSec"`
+	TotalCommandsProcessed int64   `json:"TotalCommandsProcessed"`
+	Keys                   int64   `json:"Keys"`
+	// GRAPH.MEMORY USAGE of the benchmark graph, in MB. Only present when supported by the server
+	GraphMemoryUsageMB *float64 `json:"GraphMemoryUsageMB,omitempty"`
+	// Counting scans the graph, so the counts are only taken before and after the measured phase
+	Nodes *int64 `json:"Nodes,omitempty"`
+	Edges *int64 `json:"Edges,omitempty"`
+}
+
+// ServerStatsSummary compares the server stats before and after the measured phase
+type ServerStatsSummary struct {
+	Before ServerStatsSample `json:"Before"`
+	After  ServerStatsSample `json:"After"`
+	// After minus before, for the cumulative and size fields
+	Deltas map[string]float64 `json:"Deltas"`
+	// Used memory growth, in bytes, per million write queries issued
+	MemoryGrowthPerMillionWrites float64 `json:"MemoryGrowthPerMillionWrites"`
+}
+
+// serverStatsSampler samples the database under test, on a connection that is not used by the clients.
+// Failing to sample does not stop the benchmark.
+type serverStatsSampler struct {
+	conn      *falkordb.FalkorDB
+	graph     *falkordb.Graph
+	graphName string
+	// GRAPH.MEMORY USAGE is not supported by older servers, and is no longer issued after its first failure
+	graphMemoryUsage bool
+	// samples keyed by timestamp, in milliseconds
+	mutex   sync.Mutex
+	samples map[int64]interface{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newServerStatsSampler(conn *falkordb.FalkorDB, graph *falkordb.Graph, graphName string) *serverStatsSampler {
+	return &serverStatsSampler{conn: conn, graph: graph, graphName: graphName, graphMemoryUsage: true, samples: map[int64]interface{}{}}
+}
+
+// Start samples the server every interval in its own go-routine, so that sampling never delays the CLI, and exports
+// every sample when an exporter is set. The periodic samples have no node and edge counts.
+func (s *serverStatsSampler) Start(interval time.Duration, exporter *TimeSeriesExporter) {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				sample := s.Sample(now, false)
+				if exporter != nil {
+					exporter.ExportServerStats(sample)
+				}
+			}
+		}
+	}()
+}
+
+func (s *serverStatsSampler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Samples returns the samples taken so far, keyed by timestamp
+func (s *serverStatsSampler) Samples() map[int64]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.samples
+}
+
+// Sample takes a single sample. countEntities counts the nodes and edges of the graph, which scans it.
+func (s *serverStatsSampler) Sample(timestamp time.Time, countEntities bool) ServerStatsSample {
+	ctx := context.Background()
+	sample := ServerStatsSample{TimestampMillis: timestamp.UnixMilli()}
+	info, err := s.conn.Conn.Info(ctx, "memory", "cpu", "clients", "stats", "keyspace").Result()
+	if err != nil {
+		fmt.Printf("\nUnable to retrieve server INFO: %v\n", err)
+	} else {
+		fields := parseInfo(info)
+		sample.UsedMemory = infoInt(fields, "used_memory")
+		sample.UsedMemoryRss = infoInt(fields, "used_memory_rss")
+		sample.UsedCpuSys = infoFloat(fields, "used_cpu_sys")
+		sample.UsedCpuUser = infoFloat(fields, "used_cpu_user")
+		sample.ConnectedClients = infoInt(fields, "connected_clients")
+		sample.InstantaneousOpsPerSec = infoInt(fields, "instantaneous_ops_per_sec")
+		sample.TotalCommandsProcessed = infoInt(fields, "total_commands_processed")
+		sample.Keys = infoKeys(fields)
+	}
+	if s.graphMemoryUsage {
+		reply, err := s.conn.Conn.Do(ctx, "GRAPH.MEMORY", "USAGE", s.graphName).Result()
+		if usage, ok := graphMemoryUsageMB(reply); err == nil && ok {
+			sample.GraphMemoryUsageMB = &usage
+		} else {
+			// the graph might not exist yet, or the server does not support the command
+			s.graphMemoryUsage = err == nil || strings.Contains(strings.ToLower(err.Error()), "empty key")
+		}
+	}
+	if countEntities {
+		sample.Nodes = s.count("MATCH (n) RETURN count(n)")
+		sample.Edges = s.count("MATCH ()-[e]->() RETURN count(e)")
+	}
+	s.mutex.Lock()
+	s.samples[sample.TimestampMillis] = sample
+	s.mutex.Unlock()
+	return sample
+}
+
+func (s *serverStatsSampler) count(query string) *int64 {
+	result, err := s.graph.ROQuery(query, nil, nil)
+	if err != nil || !result.Next() {
+		return nil
+	}
+	count, _ := result.Record().GetByIndex(0).(int64)
+	return &count
+}
+
+// parseInfo returns the fields of an INFO reply
+func parseInfo(info string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, found := strings.Cut(line, ":"); found {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+func infoInt(fields map[string]string, key string) int64 {
+	value, _ := strconv.ParseInt(fields[key], 10, 64)
+	return value
+}
+
+func infoFloat(fields map[string]string, key string) float64 {
+	value, _ := strconv.ParseFloat(fields[key], 64)
+	return value
+}
+
+// infoKeys sums the keys of every database of the keyspace section, e.g. db0:keys=1,expires=0,avg_ttl=0
+func infoKeys(fields map[string]string) (keys int64) {
+	for key, value := range fields {
+		if !strings.HasPrefix(key, "db") {
+			continue
+		}
+		for _, field := range strings.Split(value, ",") {
+			if name, count, found := strings.Cut(field, "="); found && name == "keys" {
+				dbKeys, _ := strconv.ParseInt(count, 10, 64)
+				keys += dbKeys
+			}
+		}
+	}
+	return
+}
+
+// graphMemoryUsageMB reads the total graph size out of a GRAPH.MEMORY USAGE reply, either a map or a flat list of
+// field and value pairs
+func graphMemoryUsageMB(reply interface{}) (float64, bool) {
+	fields := map[string]interface{}{}
+	switch v := reply.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			fields[fmt.Sprint(key)] = value
+		}
+	case []interface{}:
+		for i := 0; i+1 < len(v); i += 2 {
+			fields[fmt.Sprint(v[i])] = v[i+1]
+		}
+	}
+	switch total := fields["total_graph_sz_mb"].(type) {
+	case int64:
+		return float64(total), true
+	case float64:
+		return total, true
+	case string:
+		value, err := strconv.ParseFloat(total, 64)
+		return value, err == nil
+	}
+	return 0, false
+}
+
+func NewServerStatsSummary(before, after ServerStatsSample, writeQueries uint64) ServerStatsSummary {
+	summary := ServerStatsSummary{Before: before, After: after}
+	summary.Deltas = map[string]float64{
+		"UsedMemory":             float64(after.UsedMemory - before.UsedMemory),
+		"UsedMemoryRss":          float64(after.UsedMemoryRss - before.UsedMemoryRss),
+		"UsedCpu":                after.UsedCpuSys + after.UsedCpuUser - before.UsedCpuSys - before.UsedCpuUser,
+		"TotalCommandsProcessed": float64(after.TotalCommandsProcessed - before.TotalCommandsProcessed),
+		"Keys":                   float64(after.Keys - before.Keys),
+	}
+	if before.Nodes != nil && after.Nodes != nil {
+		summary.Deltas["Nodes"] = float64(*after.Nodes - *before.Nodes)
+	}
+	if before.Edges != nil && after.Edges != nil {
+		summary.Deltas["Edges"] = float64(*after.Edges - *before.Edges)
+	}
+	if before.GraphMemoryUsageMB != nil && after.GraphMemoryUsageMB != nil {
+		summary.Deltas["GraphMemoryUsageMB"] = *after.GraphMemoryUsageMB - *before.GraphMemoryUsageMB
+	}
+	if writeQueries > 0 {
+		summary.MemoryGrowthPerMillionWrites = summary.Deltas["UsedMemory"] / float64(writeQueries) * 1e6
+	}
+	return summary
+}
+
+// serverStatsMetrics returns the exported fields of a sample
+func serverStatsMetrics(sample ServerStatsSample) map[string]interface{} {
+	metrics := map[string]interface{}{
+		"used_memory":               sample.UsedMemory,
+		"used_memory_rss":           sample.UsedMemoryRss,
+		"used_cpu_sys":              sample.UsedCpuSys,
+		"used_cpu_user":             sample.UsedCpuUser,
+		"connected_clients":         sample.ConnectedClients,
+		"instantaneous_ops_per_sec": sample.InstantaneousOpsPerSec,
+		"keys":                      sample.Keys,
+	}
+	if sample.Nodes != nil {
+		metrics["nodes"] = *sample.Nodes
+	}
+	if sample.Edges != nil {
+		metrics["edges"] = *sample.Edges
+	}
+	if sample.GraphMemoryUsageMB != nil {
+		metrics["graph_memory_usage_mb"] = *sample.GraphMemoryUsageMB
+	}
+	return metrics
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_parseInfo(t *testing.T) {
+	info := "# Memory\r\nused_memory:1048576\r\nused_memory_rss:2097152\r\n\r\n# CPU\r\nused_cpu_sys:1.500000\r\n\r\n# Keyspace\r\ndb0:keys=3,expires=0,avg_ttl=0\r\ndb1:keys=2,expires=1,avg_ttl=10\r\n"
+	fields := parseInfo(info)
+	if got := infoInt(fields, "used_memory"); got != 1048576 {
+		t.Errorf("used_memory = %v, want %v", got, 1048576)
+	}
+	if got := infoInt(fields, "used_memory_rss"); got != 2097152 {
+		t.Errorf("used_memory_rss = %v, want %v", got, 2097152)
+	}
+	if got := infoFloat(fields, "used_cpu_sys"); got != 1.5 {
+		t.Errorf("used_cpu_sys = %v, want %v", got, 1.5)
+	}
+	if got := infoInt(fields, "missing"); got != 0 {
+		t.Errorf("missing = %v, want %v", got, 0)
+	}
+	if got := infoKeys(fields); got != 5 {
+		t.Errorf("infoKeys() = %v, want %v", got, 5)
+	}
+}
+
+func Test_graphMemoryUsageMB(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  interface{}
+		want   float64
+		wantOk bool
+	}{
+		{"map", map[interface{}]interface{}{"total_graph_sz_mb": int64(12), "node_storage_sz_mb": int64(4)}, 12, true},
+		{"pairs", []interface{}{"node_storage_sz_mb", int64(4), "total_graph_sz_mb", "12.5"}, 12.5, true},
+		{"missing", []interface{}{"node_storage_sz_mb", int64(4)}, 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := graphMemoryUsageMB(tt.reply)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("graphMemoryUsageMB() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_NewServerStatsSummary(t *testing.T) {
+	nodesBefore, nodesAfter := int64(100), int64(90)
+	before := ServerStatsSample{UsedMemory: 1000, Nodes: &nodesBefore}
+	after := ServerStatsSample{UsedMemory: 3000, Nodes: &nodesAfter}
+	summary := NewServerStatsSummary(before, after, 1000)
+	if summary.Deltas["Nodes"] != -10 || summary.MemoryGrowthPerMillionWrites != 2e6 {
+		t.Errorf("NewServerStatsSummary() = %v, %v", summary.Deltas, summary.MemoryGrowthPerMillionWrites)
+	}
+	// the edges could not be counted
+	if _, ok := summary.Deltas["Edges"]; ok {
+		t.Errorf("NewServerStatsSummary() has an Edges delta without edge counts")
+	}
+}
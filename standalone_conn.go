@@ -11,6 +11,13 @@ import (
 
 func getStandaloneConn(graphName string, addr string, password string, tlsCaCertFile string, loadTimeout int) (graph *falkordb.Graph, conn *falkordb.FalkorDB) {
 	var err error
+	options := &falkordb.ConnectionOption{
+		Addr:        addr,
+		Password:    password,
+		ReadTimeout: time.Duration(loadTimeout) * time.Second,
+		// required for the per query client_timeout deadlines
+		ContextTimeoutEnabled: true,
+	}
 	if tlsCaCertFile != "" {
 		// Load CA cert
 		caCert, err := os.ReadFile(tlsCaCertFile)
@@ -20,44 +27,18 @@ func getStandaloneConn(graphName string, addr string, password string, tlsCaCert
 		caCertPool := x509.NewCertPool()
 		caCertPool.AppendCertsFromPEM(caCert)
 
-		clientTLSConfig := &tls.Config{
-			RootCAs:            caCertPool,
-			InsecureSkipVerify: true,
-		}
 		// InsecureSkipVerify controls whether a client verifies the
 		// server's certificate chain and host name.
 		// If InsecureSkipVerify is true, TLS accepts any certificate
 		// presented by the server and any host name in that certificate.
 		// In this mode, TLS is susceptible to man-in-the-middle attacks.
 		// This should be used only for testing.
-		if password != "" {
-			conn, err = falkordb.FalkorDBNew(&falkordb.ConnectionOption{
-				Addr:        addr,
-				Password:    password,
-				TLSConfig:   clientTLSConfig,
-				ReadTimeout: time.Duration(loadTimeout) * time.Second,
-			})
-		} else {
-			conn, err = falkordb.FalkorDBNew(&falkordb.ConnectionOption{
-				Addr:        addr,
-				TLSConfig:   clientTLSConfig,
-				ReadTimeout: time.Duration(loadTimeout) * time.Second,
-			})
-		}
-	} else {
-		if password != "" {
-			conn, err = falkordb.FalkorDBNew(&falkordb.ConnectionOption{
-				Addr:        addr,
-				Password:    password,
-				ReadTimeout: time.Duration(loadTimeout) * time.Second,
-			})
-		} else {
-			conn, err = falkordb.FalkorDBNew(&falkordb.ConnectionOption{
-				Addr:        addr,
-				ReadTimeout: time.Duration(loadTimeout) * time.Second,
-			})
+		options.TLSConfig = &tls.Config{
+			RootCAs:            caCertPool,
+			InsecureSkipVerify: true,
 		}
 	}
+	conn, err = falkordb.FalkorDBNew(options)
 
 	if err != nil {
 		log.Panicf("Error preparing for benchmark, while creating new connection. error = %v", err)
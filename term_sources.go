@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	termModeSeq         = "seq"
+	termModeRand        = "rand"
+	termModeZipf        = "zipf"
+	termModePartitioned = "partitioned"
+
+	termFormatCsv   = "csv"
+	termFormatTsv   = "tsv"
+	termFormatJsonl = "jsonl"
+
+	termTypeString = "string"
+	termTypeInt    = "int"
+	termTypeFloat  = "float"
+	termTypeBool   = "bool"
+
+	defaultZipfS = 1.1
+
+	// name of the term source given by the --data-import-terms flag
+	dataImportTermsSourceName = "data-import-terms"
+)
+
+// termRow is a single line of a term source, typed and rendered once at startup
+type termRow struct {
+	// placeholder ( and param name ) to term
+	terms map[string]string
+	// the line Cypher params, e.g. ` id=1 name="a"`
+	params string
+}
+
+// termSource holds the distinct lines of a term source, once, whatever the number of requests
+type termSource struct {
+	name  string
+	mode  string
+	zipfS float64
+	// names of the queries using the source, all of them when empty
+	queries      []string
+	placeholders []string
+	rows         []*termRow
+}
+
+// termSourceFormat returns the configured format, or the one matching the file extension
+func termSourceFormat(config TermSourceConfig) string {
+	if config.Format != "" {
+		return config.Format
+	}
+	switch strings.ToLower(filepath.Ext(config.File)) {
+	case ".tsv", ".tab":
+		return termFormatTsv
+	case ".jsonl", ".ndjson":
+		return termFormatJsonl
+	}
+	return termFormatCsv
+}
+
+func loadTermSource(config TermSourceConfig) (*termSource, error) {
+	headers, records, err := readTermRecords(config.File, termSourceFormat(config))
+	if err != nil {
+		return nil, fmt.Errorf("term source %s: %v", config.Name, err)
+	}
+	return newTermSource(config, headers, records)
+}
+
+// loadCypherTermSource generates the terms from the benchmark graph, so it must run once the dataset is loaded
+func loadCypherTermSource(config TermSourceConfig, graph *falkordb.Graph, seed int64) (*termSource, error) {
+	headers, records, err := cypherTermRecords(graph, config.Cypher, config.SampleSize, seed)
+	if err != nil {
+		return nil, fmt.Errorf("term source %s: %v", config.Name, err)
+	}
+	return newTermSource(config, headers, records)
+}
+
+func newTermSource(config TermSourceConfig, headers []string, records [][]string) (*termSource, error) {
+	columns := config.Columns
+	if len(columns) == 0 {
+		for _, header := range headers {
+			columns = append(columns, TermColumn{Name: header, Placeholder: header, Type: termTypeString})
+		}
+	}
+	columnPos := make([]int, len(columns))
+	for i, column := range columns {
+		columnPos[i] = slices.Index(headers, column.Name)
+		if columnPos[i] == -1 {
+			return nil, fmt.Errorf("term source %s: column %s not found, available columns are %v", config.Name, column.Name, headers)
+		}
+	}
+	source := &termSource{name: config.Name, mode: config.Mode, zipfS: config.ZipfS, queries: config.Queries, rows: make([]*termRow, len(records))}
+	for _, column := range columns {
+		if column.Placeholder != "" {
+			source.placeholders = append(source.placeholders, column.Placeholder)
+		}
+	}
+	for i, record := range records {
+		row := &termRow{terms: make(map[string]string, len(columns))}
+		var params strings.Builder
+		for j, column := range columns {
+			term := record[columnPos[j]]
+			value, err := cypherParamValue(term, column.Type)
+			if err != nil {
+				return nil, fmt.Errorf("term source %s: line %d, column %s: %v", config.Name, i+1, column.Name, err)
+			}
+			if column.Placeholder != "" {
+				row.terms[column.Placeholder] = term
+			}
+			if column.Param != "" {
+				row.terms[column.Param] = term
+				fmt.Fprintf(&params, " %s=%s", column.Param, value)
+			}
+		}
+		row.params = params.String()
+		source.rows[i] = row
+	}
+	return source, nil
+}
+
+// readTermRecords returns the column names and the lines of a CSV, TSV or JSON Lines file
+func readTermRecords(filename string, format string) (headers []string, records [][]string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	switch format {
+	case termFormatCsv, termFormatTsv:
+		reader := csv.NewReader(f)
+		if format == termFormatTsv {
+			reader.Comma = '\t'
+			reader.LazyQuotes = true
+		}
+		records, err = reader.ReadAll()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse %s as %s: %v", filename, format, err)
+		}
+		if len(records) > 0 {
+			headers, records = records[0], records[1:]
+		}
+	case termFormatJsonl:
+		headers, records, err = readJsonLines(filename, bufio.NewReader(f))
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %s", format)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s has no term lines, only a header line is present", filename)
+	}
+	return headers, records, nil
+}
+
+// readJsonLines takes the column names from the keys of the first object. Missing keys and nulls are empty terms.
+func readJsonLines(filename string, reader *bufio.Reader) (headers []string, records [][]string, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+		decoder.UseNumber()
+		object := map[string]interface{}{}
+		if err = decoder.Decode(&object); err != nil {
+			return nil, nil, fmt.Errorf("unable to parse %s line %d as a JSON object: %v", filename, line, err)
+		}
+		if headers == nil {
+			for key := range object {
+				headers = append(headers, key)
+			}
+			sort.Strings(headers)
+		}
+		record := make([]string, len(headers))
+		for i, key := range headers {
+			record[i] = jsonTerm(object[key])
+		}
+		records = append(records, record)
+	}
+	return headers, records, scanner.Err()
+}
+
+// cypherTermRecords runs the read-only query on the benchmark graph, and keeps all of its rows, or a uniform reservoir
+// sample of sampleSize rows
+func cypherTermRecords(graph *falkordb.Graph, query string, sampleSize int, seed int64) (headers []string, records [][]string, err error) {
+	queryResult, err := graph.ROQuery(query, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to run '%s': %v", query, err)
+	}
+	headers, rows := resultSetRows(queryResult)
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("'%s' returned no rows", query)
+	}
+	for _, row := range reservoirSample(rows, sampleSize, seed) {
+		record := make([]string, len(row))
+		for j, value := range row {
+			if record[j], err = cypherTerm(value); err != nil {
+				return nil, nil, fmt.Errorf("column %s of '%s': %v", headers[j], query, err)
+			}
+		}
+		records = append(records, record)
+	}
+	return headers, records, nil
+}
+
+// reservoirSample keeps a uniform sample of sampleSize rows, in a single pass. A sampleSize of 0 keeps every row.
+func reservoirSample(rows [][]interface{}, sampleSize int, seed int64) [][]interface{} {
+	if sampleSize == 0 || len(rows) <= sampleSize {
+		return rows
+	}
+	random := rand.New(rand.NewSource(seed))
+	sample := append([][]interface{}{}, rows[:sampleSize]...)
+	for i := sampleSize; i < len(rows); i++ {
+		// the row replaces a kept one with a probability of sampleSize / ( i + 1 )
+		if pos := random.Intn(i + 1); pos < sampleSize {
+			sample[pos] = rows[i]
+		}
+	}
+	return sample
+}
+
+// cypherTerm converts a result set value to a term. Graph entities are not supported, their properties should be returned instead.
+func cypherTerm(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case *falkordb.Node, *falkordb.Edge, falkordb.Path, *falkordb.Path:
+		return "", fmt.Errorf("graph entities can't be used as terms, return one of their properties instead")
+	}
+	return jsonTerm(value), nil
+}
+
+func jsonTerm(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// cypherParamValue checks the term against the column type, and returns it as a Cypher literal
+func cypherParamValue(term string, columnType string) (string, error) {
+	switch columnType {
+	case termTypeInt:
+		value, err := strconv.ParseInt(strings.TrimSpace(term), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("'%s' is not an int", term)
+		}
+		return strconv.FormatInt(value, 10), nil
+	case termTypeFloat:
+		value, err := strconv.ParseFloat(strings.TrimSpace(term), 64)
+		if err != nil {
+			return "", fmt.Errorf("'%s' is not a float", term)
+		}
+		return cypherFloat(value), nil
+	case termTypeBool:
+		value, err := strconv.ParseBool(strings.TrimSpace(term))
+		if err != nil {
+			return "", fmt.Errorf("'%s' is not a bool", term)
+		}
+		return strconv.FormatBool(value), nil
+	}
+	return cypherString(term), nil
+}
+
+// cypherFloat writes a float literal, that Cypher doesn't take for an int
+func cypherFloat(value float64) string {
+	literal := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(literal, ".") {
+		literal += ".0"
+	}
+	return literal
+}
+
+// cypherStringReplacer escapes a Cypher string literal between double quotes
+var cypherStringReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func cypherString(value string) string {
+	return `"` + cypherStringReplacer.Replace(value) + `"`
+}
+
+// cypherName quotes a property key, or any other Cypher name, between backticks
+func cypherName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// bindTermSources returns the positions of the sources used by each query, and the placeholders of each query
+func bindTermSources(sources []*termSource, queryNames []string, clients uint64) (querySources [][]int, queryPlaceholders [][]string, err error) {
+	querySources = make([][]int, len(queryNames))
+	queryPlaceholders = make([][]string, len(queryNames))
+	for s, source := range sources {
+		if source.mode == termModePartitioned && uint64(len(source.rows)) < clients {
+			return nil, nil, fmt.Errorf("term source %s is partitioned between %d clients, but only has %d lines", source.name, clients, len(source.rows))
+		}
+		for i, queryName := range queryNames {
+			if len(source.queries) > 0 && (queryName == "" || slices.Index(source.queries, queryName) == -1) {
+				continue
+			}
+			for _, placeholder := range source.placeholders {
+				if slices.Index(queryPlaceholders[i], placeholder) != -1 {
+					return nil, nil, fmt.Errorf("placeholder %s of term source %s is already provided by another source of query %s", placeholder, source.name, queryName)
+				}
+			}
+			querySources[i] = append(querySources[i], s)
+			queryPlaceholders[i] = append(queryPlaceholders[i], source.placeholders...)
+		}
+	}
+	return querySources, queryPlaceholders, nil
+}
+
+// termsPicker picks the lines of a term source for a single client, on the fly
+type termsPicker struct {
+	source *termSource
+	random *rand.Rand
+	zipf   *rand.Zipf
+	// next line position, wrapped around the client partition [start, end)
+	next, start, end uint64
+}
+
+// newTermsPicker starts a seq mode client at its first request position, so that the clients cover the lines in
+// order between them. In partitioned mode every client goes through its own contiguous share of the lines. In rand
+// and zipf modes every client has its own random source derived from the seed.
+func newTermsPicker(source *termSource, clientId uint64, clients uint64, commandStartPos uint64, seed int64) *termsPicker {
+	lines := uint64(len(source.rows))
+	picker := &termsPicker{source: source, next: commandStartPos, start: 0, end: lines}
+	switch source.mode {
+	case termModeRand:
+		picker.random = rand.New(rand.NewSource(seed))
+	case termModeZipf:
+		picker.random = rand.New(rand.NewSource(seed))
+		picker.zipf = rand.NewZipf(picker.random, source.zipfS, 1, lines-1)
+	case termModePartitioned:
+		picker.start = lines * clientId / clients
+		picker.end = lines * (clientId + 1) / clients
+		picker.next = 0
+	}
+	return picker
+}
+
+func (p *termsPicker) pick() *termRow {
+	if p.zipf != nil {
+		return p.source.rows[p.zipf.Uint64()]
+	}
+	if p.random != nil {
+		return p.source.rows[p.random.Intn(len(p.source.rows))]
+	}
+	row := p.source.rows[p.start+p.next%(p.end-p.start)]
+	p.next++
+	return row
+}
+
+// mergedTerms returns the terms of all the lines used by a request
+func mergedTerms(termRows []*termRow) map[string]string {
+	switch len(termRows) {
+	case 0:
+		return nil
+	case 1:
+		return termRows[0].terms
+	}
+	terms := map[string]string{}
+	for _, row := range termRows {
+		for placeholder, term := range row.terms {
+			terms[placeholder] = term
+		}
+	}
+	return terms
+}
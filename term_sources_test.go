@@ -0,0 +1,198 @@
+package main
+
+import (
+	"github.com/FalkorDB/falkordb-go"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTermsFile(t *testing.T, name string, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func Test_readTermRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		wantHeaders []string
+		wantRecords [][]string
+		wantErr     bool
+	}{
+		{"csv", "terms.csv", "__id__,__name__\n1,a\n2,\"b,c\"\n", []string{"__id__", "__name__"}, [][]string{{"1", "a"}, {"2", "b,c"}}, false},
+		{"tsv", "terms.tsv", "id\tname\n1\ta \"b\"\n", []string{"id", "name"}, [][]string{{"1", "a \"b\""}}, false},
+		{"jsonl", "terms.jsonl", "{\"name\": \"a\", \"id\": 1, \"vip\": true}\n\n{\"id\": 2.5, \"tags\": [\"x\"]}\n", []string{"id", "name", "vip"}, [][]string{{"1", "a", "true"}, {"2.5", "", ""}}, false},
+		{"header-only", "terms.csv", "__id__,__name__\n", nil, nil, true},
+		{"empty", "terms.jsonl", "", nil, nil, true},
+		{"missing-column", "terms.csv", "__id__,__name__\n1\n", nil, nil, true},
+		{"invalid-json", "terms.jsonl", "{\"id\": 1\n", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeTermsFile(t, tt.file, tt.content)
+			headers, records, err := readTermRecords(filename, termSourceFormat(TermSourceConfig{File: filename}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readTermRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(headers, tt.wantHeaders) || !reflect.DeepEqual(records, tt.wantRecords) {
+				t.Errorf("readTermRecords() = %v, %v, want %v, %v", headers, records, tt.wantHeaders, tt.wantRecords)
+			}
+		})
+	}
+}
+
+func Test_cypherParamValue(t *testing.T) {
+	tests := []struct {
+		term       string
+		columnType string
+		want       string
+		wantErr    bool
+	}{
+		{"a 'b' \"c\"", termTypeString, "\"a 'b' \\\"c\\\"\"", false},
+		{"a\\b\x01é", termTypeString, "\"a\\\\b\x01é\"", false},
+		{" 42 ", termTypeInt, "42", false},
+		{"4.2", termTypeInt, "", true},
+		{"4", termTypeFloat, "4.0", false},
+		{"1e3", termTypeFloat, "1000.0", false},
+		{"0.25", termTypeFloat, "0.25", false},
+		{"nan-ish", termTypeFloat, "", true},
+		{"TRUE", termTypeBool, "true", false},
+		{"yes", termTypeBool, "", true},
+	}
+	for _, tt := range tests {
+		got, err := cypherParamValue(tt.term, tt.columnType)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("cypherParamValue(%v, %v) = %v, %v, want %v, error %v", tt.term, tt.columnType, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func Test_loadTermSource(t *testing.T) {
+	filename := writeTermsFile(t, "people.csv", "id,name,score\n1,Ann,0.5\n2,Bob,x\n")
+	columns := []TermColumn{{Name: "id", Placeholder: "__id__", Param: "id", Type: termTypeInt}, {Name: "name", Param: "name", Type: termTypeString}}
+	source, err := loadTermSource(TermSourceConfig{Name: "people", File: filename, Columns: columns})
+	if err != nil {
+		t.Fatalf("loadTermSource() error = %v", err)
+	}
+	if !reflect.DeepEqual(source.placeholders, []string{"__id__"}) {
+		t.Errorf("loadTermSource() placeholders = %v, want [__id__]", source.placeholders)
+	}
+	if got := source.rows[1]; got.params != " id=2 name=\"Bob\"" || got.terms["__id__"] != "2" || got.terms["name"] != "Bob" {
+		t.Errorf("loadTermSource() second row = %v", got)
+	}
+	if _, err = loadTermSource(TermSourceConfig{Name: "people", File: filename, Columns: []TermColumn{{Name: "score", Param: "score", Type: termTypeFloat}}}); err == nil {
+		t.Errorf("loadTermSource() expected an error on a float column holding 'x'")
+	}
+	if _, err = loadTermSource(TermSourceConfig{Name: "people", File: filename, Columns: []TermColumn{{Name: "age", Placeholder: "__age__"}}}); err == nil {
+		t.Errorf("loadTermSource() expected an error on a missing column")
+	}
+}
+
+func Test_cypherTerm(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{int64(42), "42", false},
+		{0.25, "0.25", false},
+		{"Ann", "Ann", false},
+		{true, "true", false},
+		{nil, "", false},
+		{[]interface{}{int64(1), "a"}, "[1,\"a\"]", false},
+		{&falkordb.Node{}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := cypherTerm(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("cypherTerm(%v) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func Test_reservoirSample(t *testing.T) {
+	rows := make([][]interface{}, 1000)
+	for i := range rows {
+		rows[i] = []interface{}{int64(i)}
+	}
+	if got := reservoirSample(rows, 0, 12345); len(got) != len(rows) {
+		t.Errorf("reservoirSample() with no sample size kept %d rows, want %d", len(got), len(rows))
+	}
+	if got := reservoirSample(rows[:10], 20, 12345); len(got) != 10 {
+		t.Errorf("reservoirSample() of fewer rows than the sample size kept %d rows, want 10", len(got))
+	}
+	sample := reservoirSample(rows, 100, 12345)
+	if len(sample) != 100 {
+		t.Fatalf("reservoirSample() kept %d rows, want 100", len(sample))
+	}
+	if !reflect.DeepEqual(sample, reservoirSample(rows, 100, 12345)) {
+		t.Errorf("reservoirSample() is not deterministic for a given seed")
+	}
+	seen := map[int64]bool{}
+	beyondSample := 0
+	for _, row := range sample {
+		id := row[0].(int64)
+		if seen[id] {
+			t.Errorf("reservoirSample() kept row %d twice", id)
+		}
+		seen[id] = true
+		if id >= 100 {
+			beyondSample++
+		}
+	}
+	// 90% of the rows are expected past the first 100 ones
+	if beyondSample < 75 {
+		t.Errorf("reservoirSample() kept only %d rows past the first 100, the sample is not uniform", beyondSample)
+	}
+}
+
+func Test_bindTermSources(t *testing.T) {
+	people := &termSource{name: "people", placeholders: []string{"__id__"}, rows: []*termRow{{}, {}}}
+	cities := &termSource{name: "cities", queries: []string{"q2"}, placeholders: []string{"__city__"}, rows: []*termRow{{}}}
+	querySources, queryPlaceholders, err := bindTermSources([]*termSource{people, cities}, []string{"q1", "q2", ""}, 2)
+	if err != nil {
+		t.Fatalf("bindTermSources() error = %v", err)
+	}
+	if !reflect.DeepEqual(querySources, [][]int{{0}, {0, 1}, {0}}) || !reflect.DeepEqual(queryPlaceholders[1], []string{"__id__", "__city__"}) {
+		t.Errorf("bindTermSources() = %v, %v", querySources, queryPlaceholders)
+	}
+	duplicated := &termSource{name: "other-people", placeholders: []string{"__id__"}, rows: []*termRow{{}}}
+	if _, _, err = bindTermSources([]*termSource{people, duplicated}, []string{"q1"}, 1); err == nil {
+		t.Errorf("bindTermSources() expected an error on a placeholder provided twice")
+	}
+	partitioned := &termSource{name: "partitioned", mode: termModePartitioned, rows: []*termRow{{}}}
+	if _, _, err = bindTermSources([]*termSource{partitioned}, []string{"q1"}, 2); err == nil {
+		t.Errorf("bindTermSources() expected an error on less lines than clients")
+	}
+}
+
+func Test_termsPicker(t *testing.T) {
+	rows := make([]*termRow, 5)
+	for i := range rows {
+		rows[i] = &termRow{params: string(rune('0' + i))}
+	}
+	picks := func(picker *termsPicker, n int) (got string) {
+		for i := 0; i < n; i++ {
+			got += picker.pick().params
+		}
+		return
+	}
+	if got := picks(newTermsPicker(&termSource{mode: termModeSeq, rows: rows}, 1, 2, 3, 12345), 4); got != "3401" {
+		t.Errorf("seq picks = %v, want %v", got, "3401")
+	}
+	if got := picks(newTermsPicker(&termSource{mode: termModePartitioned, rows: rows}, 1, 2, 3, 12345), 4); got != "2342" {
+		t.Errorf("partitioned picks = %v, want %v", got, "2342")
+	}
+	for _, mode := range []string{termModeRand, termModeZipf} {
+		source := &termSource{mode: mode, zipfS: defaultZipfS, rows: rows}
+		if first, second := picks(newTermsPicker(source, 0, 1, 0, 12345), 100), picks(newTermsPicker(source, 0, 1, 0, 12345), 100); first != second {
+			t.Errorf("%s picks with the same seed differ", mode)
+		}
+	}
+}
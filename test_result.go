@@ -7,25 +7,85 @@ import (
 	"log"
 	"math"
 	"os"
-	"sync"
 	"sync/atomic"
 	"time"
 )
 
-const resultFormatVersion = "0.0.1"
+const resultFormatVersion = "0.1.0"
 
 type GraphQueryDatapoint struct {
 	CmdPos                      int // command that was used
 	ClientDurationMicros        int64
 	GraphInternalDurationMicros int64
 	Error                       bool
-	Empty                       bool
-	NodesCreated                uint64
-	NodesDeleted                uint64
-	LabelsAdded                 uint64
-	PropertiesSet               uint64
-	RelationshipsCreated        uint64
-	RelationshipsDeleted        uint64
+	ErrorClass                  string
+	ErrorMessage                string
+	ErrorTimestampMillis        int64
+	// the error matched one of the query expected errors, and counts as a success
+	ExpectedError bool
+	// the datapoint belongs to a retry, and not to the first attempt of the request
+	Retry bool
+	// the request failed, and won't be retried
+	RequestFailed bool
+	// the result set was validated, and the violated expectation if any
+	Validated           bool
+	ValidationViolation string
+	Rows                uint64
+	// the query reused a cached execution plan
+	CachedExecution      bool
+	ReplyBytes           uint64
+	Empty                bool
+	NodesCreated         uint64
+	NodesDeleted         uint64
+	LabelsAdded          uint64
+	PropertiesSet        uint64
+	RelationshipsCreated uint64
+	RelationshipsDeleted uint64
+}
+
+// QueryStats holds the configuration and the results of a single benchmarked query
+type QueryStats struct {
+	Id                     string             `json:"Id"`
+	Name                   string             `json:"Name"`
+	Query                  string             `json:"Query"`
+	ReadOnly               bool               `json:"ReadOnly"`
+	Ratio                  float64            `json:"Ratio"`
+	Totals                 interface{}        `json:"Totals"`
+	QueryRate              float64            `json:"QueryRate"`
+	ClientLatencies        map[string]float64 `json:"ClientLatencies"`
+	GraphInternalLatencies map[string]float64 `json:"GraphInternalLatencies"`
+	// Client latencies of the requests that returned an error
+	FailedClientLatencies map[string]float64 `json:"FailedClientLatencies"`
+	// Errors grouped by class
+	ErrorClasses map[string]*ErrorClassStats `json:"ErrorClasses"`
+	// Errors that matched the query expected errors, and counted as a success
+	ExpectedErrors uint64 `json:"ExpectedErrors"`
+	// Requests that failed after all their retries
+	FailedRequests uint64     `json:"FailedRequests"`
+	Retries        RetryStats `json:"Retries"`
+	// Rows and approximate reply size per successful request
+	ResultSize ResultSizeStats `json:"ResultSize"`
+	PlanCache  PlanCacheStats  `json:"PlanCache"`
+	// Plans captured with a representative set of parameters
+	ExecutionPlan QueryExecutionPlan `json:"ExecutionPlan"`
+	// Result set validation, only present for the queries with expectations
+	Validation *ValidationStats `json:"Validation,omitempty"`
+}
+
+// QueryExecutionPlan holds the GRAPH.EXPLAIN and GRAPH.PROFILE output lines of a query
+type QueryExecutionPlan struct {
+	// The query, with the parameters used to capture the plans
+	Query   string   `json:"Query"`
+	Explain []string `json:"Explain"`
+	Profile []string `json:"Profile,omitempty"`
+}
+
+// RetryStats holds the stats of the retries of transient errors, reported apart from the first attempts
+type RetryStats struct {
+	Attempts        uint64             `json:"Attempts"`
+	Errors          uint64             `json:"Errors"`
+	Recovered       uint64             `json:"Recovered"`
+	ClientLatencies map[string]float64 `json:"ClientLatencies"`
 }
 
 type TestResult struct {
@@ -39,6 +99,8 @@ type TestResult struct {
 	BenchmarkConfiguredCommandsLimit uint64 `json:"BenchmarkConfiguredCommandsLimit"`
 	IssuedCommands                   uint64 `json:"IssuedCommands"`
 	BenchmarkFullyRun                bool   `json:"BenchmarkFullyRun"`
+	// Why the benchmark stopped: completed, interrupted, database process exited, error budget exceeded, or the error that stopped it
+	StopReason string `json:"StopReason"`
 
 	// Test Description
 	TestDescription string `json:"TestDescription"`
@@ -51,9 +113,21 @@ type TestResult struct {
 	DurationMillis int64 `json:"DurationMillis"`
 
 	// Populated after benchmark
+	// Per query configuration and stats
+	Queries []QueryStats `json:"Queries"`
+
 	// Benchmark Totals
 	Totals map[string]interface{} `json:"Totals"`
 
+	// Errors of all queries grouped by class
+	ErrorClasses   map[string]*ErrorClassStats `json:"ErrorClasses"`
+	ExpectedErrors uint64                      `json:"ExpectedErrors"`
+	FailedRequests uint64                      `json:"FailedRequests"`
+	Retries        RetryStats                  `json:"Retries"`
+	Validation     *ValidationStats            `json:"Validation,omitempty"`
+	ResultSize     ResultSizeStats             `json:"ResultSize"`
+	PlanCache      PlanCacheStats              `json:"PlanCache"`
+
 	// Overall Rates
 	OverallQueryRates map[string]interface{} `json:"OverallQueryRates"`
 
@@ -63,6 +137,9 @@ type TestResult struct {
 	// Overall Graph Internal Quantiles
 	OverallGraphInternalLatencies map[string]interface{} `json:"OverallGraphInternalLatencies"`
 
+	// Overall Client Quantiles of the requests that returned an error
+	OverallFailedClientLatencies map[string]interface{} `json:"OverallFailedClientLatencies"`
+
 	// Relative Internal External Latencies Differences
 	RelativeInternalExternalLatencyDiff map[string]float64 `json:"OverallRelativeInternalExternalLatencyDiff"`
 
@@ -72,8 +149,23 @@ type TestResult struct {
 	// Per second ( tick ) client stats
 	ClientRunTimeStats map[int64]interface{} `json:"ClientRunTimeStats"`
 
+	// Whether the benchmark client itself was the bottleneck
+	ClientStatsSummary ClientStatsSummary `json:"ClientStatsSummary"`
+
 	// Per second ( tick ) server stats
 	ServerRunTimeStats map[int64]interface{} `json:"ServerRunTimeStats"`
+
+	// Resource usage of the database process during the measured phase, when it could be sampled
+	DatabaseProcessStats *ProcessStatsSummary `json:"DatabaseProcessStats,omitempty"`
+
+	// Server stats before and after the measured phase
+	ServerStatsSummary ServerStatsSummary `json:"ServerStatsSummary"`
+
+	// Data of an RDB dataset, as loaded
+	Dataset *DatasetIdentity `json:"Dataset,omitempty"`
+
+	// Load of a generated or CSV dataset, before the measured phase
+	Ingestion *IngestionStats `json:"Ingestion,omitempty"`
 }
 
 func NewTestResult(metadata string, clients uint64, commandsLimit uint64, maxRps uint64, testDescription string) *TestResult {
@@ -91,67 +183,27 @@ func (r *TestResult) FillDurationInfo(startTime time.Time, endTime time.Time, du
 	r.DurationMillis = duration.Milliseconds()
 }
 
-func processGraphDatapointsChannel(graphStatsChann chan GraphQueryDatapoint, c chan os.Signal, numberRequests uint64, wg *sync.WaitGroup, instantMutex *sync.Mutex) {
-	defer wg.Done()
-	var totalProcessedCommands uint64 = 0
-	for {
-		select {
-		case dp := <-graphStatsChann:
-			{
-				cmdPos := dp.CmdPos
-				clientDurationMicros := dp.ClientDurationMicros
-				instantMutex.Lock()
-				clientSidePerQueryOverallLatencies[cmdPos].RecordValue(clientDurationMicros)
-				clientSideAllQueriesOverallLatencies.RecordValue(clientDurationMicros)
-				graphInternalDurationMicros := dp.GraphInternalDurationMicros
-				serverSidePerQueryGraphInternalTimeOverallLatencies[cmdPos].RecordValue(graphInternalDurationMicros)
-				serverSideAllQueriesGraphInternalTimeOverallLatencies.RecordValue(graphInternalDurationMicros)
-				instantMutex.Unlock()
-				// Only needs to be atomic due to CLI print
-				atomic.AddUint64(&totalCommands, uint64(1))
-				if dp.Error {
-					// Only needs to be atomic due to CLI print
-					atomic.AddUint64(&totalErrors, uint64(1))
-					errorsPerQuery[cmdPos]++
-				} else {
-					totalNodesCreated = totalNodesCreated + dp.NodesCreated
-					totalNodesDeleted = totalNodesDeleted + dp.NodesDeleted
-					totalLabelsAdded = totalLabelsAdded + dp.LabelsAdded
-					totalPropertiesSet = totalPropertiesSet + dp.PropertiesSet
-					totalRelationshipsCreated = totalRelationshipsCreated + dp.RelationshipsCreated
-					totalRelationshipsDeleted = totalRelationshipsDeleted + dp.RelationshipsDeleted
-
-					totalNodesCreatedPerQuery[cmdPos] = totalNodesCreatedPerQuery[cmdPos] + dp.NodesCreated
-					totalNodesDeletedPerQuery[cmdPos] = totalNodesDeletedPerQuery[cmdPos] + dp.NodesDeleted
-					totalLabelsAddedPerQuery[cmdPos] = totalLabelsAddedPerQuery[cmdPos] + dp.LabelsAdded
-					totalPropertiesSetPerQuery[cmdPos] = totalPropertiesSetPerQuery[cmdPos] + dp.PropertiesSet
-					totalRelationshipsCreatedPerQuery[cmdPos] = totalRelationshipsCreatedPerQuery[cmdPos] + dp.RelationshipsCreated
-					totalRelationshipsDeletedPerQuery[cmdPos] = totalRelationshipsDeletedPerQuery[cmdPos] + dp.RelationshipsDeleted
-
-					if dp.Empty {
-						totalEmptyResultsets++
-					}
-				}
-
-				instantMutex.Lock()
-				clientSideAllQueriesInstantLatencies.RecordValue(clientDurationMicros)
-				serverSideAllQueriesGraphInternalTimeInstantLatencies.RecordValue(graphInternalDurationMicros)
-				instantMutex.Unlock()
-
-				totalProcessedCommands++
-				// if all commands have been processed return
-				// otherwise keep looping
-				if totalProcessedCommands >= numberRequests {
-					return
-				}
-				break
-			}
-
-		case <-c:
-			fmt.Println("\nReceived Ctrl-c - shutting down datapoints processor go-routine")
-			return
-		}
-	}
+// recordWriteStats accounts the entities written by the successful requests of a worker interval
+func recordWriteStats(cmdPos int, stats *workerQueryStats) {
+	// Only needs to be atomic due to the metrics endpoint
+	atomic.AddUint64(&totalNodesCreated, stats.nodesCreated)
+	atomic.AddUint64(&totalNodesDeleted, stats.nodesDeleted)
+	atomic.AddUint64(&totalLabelsAdded, stats.labelsAdded)
+	atomic.AddUint64(&totalPropertiesSet, stats.propertiesSet)
+	atomic.AddUint64(&totalRelationshipsCreated, stats.relationshipsCreated)
+	atomic.AddUint64(&totalRelationshipsDeleted, stats.relationshipsDeleted)
+
+	totalNodesCreatedPerQuery[cmdPos] = totalNodesCreatedPerQuery[cmdPos] + stats.nodesCreated
+	totalNodesDeletedPerQuery[cmdPos] = totalNodesDeletedPerQuery[cmdPos] + stats.nodesDeleted
+	totalLabelsAddedPerQuery[cmdPos] = totalLabelsAddedPerQuery[cmdPos] + stats.labelsAdded
+	totalPropertiesSetPerQuery[cmdPos] = totalPropertiesSetPerQuery[cmdPos] + stats.propertiesSet
+	totalRelationshipsCreatedPerQuery[cmdPos] = totalRelationshipsCreatedPerQuery[cmdPos] + stats.relationshipsCreated
+	totalRelationshipsDeletedPerQuery[cmdPos] = totalRelationshipsDeletedPerQuery[cmdPos] + stats.relationshipsDeleted
+}
+
+func NewRetryStats(attempts, errors, recovered uint64, latencies *hdrhistogram.Histogram) RetryStats {
+	_, latenciesMap := generateLatenciesMap(latencies)
+	return RetryStats{Attempts: attempts, Errors: errors, Recovered: recovered, ClientLatencies: latenciesMap}
 }
 
 func saveJsonResult(testResult *TestResult, jsonOutputFile string) {
@@ -172,6 +224,11 @@ func calculateRateMetrics(current, prev int64, took time.Duration) (rate float64
 }
 
 func generateLatenciesMap(hist *hdrhistogram.Histogram) (int64, map[string]float64) {
+	// latencies are recorded in microseconds, and reported in milliseconds
+	return generateQuantilesMap(hist, 10e2)
+}
+
+func generateQuantilesMap(hist *hdrhistogram.Histogram, scale float64) (int64, map[string]float64) {
 	ops := hist.TotalCount()
 	q0 := 0.0
 	q50 := 0.0
@@ -181,28 +238,26 @@ func generateLatenciesMap(hist *hdrhistogram.Histogram) (int64, map[string]float
 	q100 := 0.0
 	average := 0.0
 	if ops > 0 {
-		q0 = float64(hist.ValueAtQuantile(0.0)) / 10e2
-		q50 = float64(hist.ValueAtQuantile(50.0)) / 10e2
-		q95 = float64(hist.ValueAtQuantile(95.0)) / 10e2
-		q99 = float64(hist.ValueAtQuantile(99.0)) / 10e2
-		q999 = float64(hist.ValueAtQuantile(99.90)) / 10e2
-		q100 = float64(hist.ValueAtQuantile(100.0)) / 10e2
-		average = (hist.Mean() / float64(1000.0))
+		q0 = float64(hist.ValueAtQuantile(0.0)) / scale
+		q50 = float64(hist.ValueAtQuantile(50.0)) / scale
+		q95 = float64(hist.ValueAtQuantile(95.0)) / scale
+		q99 = float64(hist.ValueAtQuantile(99.0)) / scale
+		q999 = float64(hist.ValueAtQuantile(99.90)) / scale
+		q100 = float64(hist.ValueAtQuantile(100.0)) / scale
+		average = (hist.Mean() / scale)
 	}
 
 	mp := map[string]float64{"q0": q0, "q50": q50, "q95": q95, "q99": q99, "q999": q999, "q100": q100, "avg": average}
 	return ops, mp
 }
 
-func GetOverallLatencies(cmds []string, perQueryHistograms []*hdrhistogram.Histogram, totalsHistogram *hdrhistogram.Histogram) (map[string]interface{}, map[string]float64) {
-	perQueryQuantileMap := map[string]interface{}{}
-	for i, query := range cmds {
-		_, quantileMap := generateLatenciesMap(perQueryHistograms[i])
-		perQueryQuantileMap[query] = quantileMap
+func GetOverallLatencies(perQueryHistograms []*hdrhistogram.Histogram, totalsHistogram *hdrhistogram.Histogram) ([]map[string]float64, map[string]float64) {
+	perQueryQuantiles := make([]map[string]float64, len(perQueryHistograms))
+	for i, histogram := range perQueryHistograms {
+		_, perQueryQuantiles[i] = generateLatenciesMap(histogram)
 	}
 	_, totalMap := generateLatenciesMap(totalsHistogram)
-	perQueryQuantileMap["Total"] = totalMap
-	return perQueryQuantileMap, totalMap
+	return perQueryQuantiles, totalMap
 }
 
 func GenerateInternalExternalRatioLatencies(internal map[string]float64, external map[string]float64) (ratioMap map[string]float64, absoluteMap map[string]float64) {
@@ -223,30 +278,24 @@ func GenerateInternalExternalRatioLatencies(internal map[string]float64, externa
 	return
 }
 
-func GetOverallRatesMap(took time.Duration, cmds []string, perQueryHistograms []*hdrhistogram.Histogram, totalsHistogram *hdrhistogram.Histogram) map[string]interface{} {
+func GetOverallRates(took time.Duration, commandsPerQuery []uint64, totalCommands uint64) ([]float64, float64) {
 	/////////
 	// Overall Rates
 	/////////
-	perQueryRatesMap := map[string]interface{}{}
-	for i, query := range cmds {
-		count := perQueryHistograms[i].TotalCount()
-		rate := calculateRateMetrics(count, 0, took)
-		perQueryRatesMap[query] = rate
+	perQueryRates := make([]float64, len(commandsPerQuery))
+	for i, commands := range commandsPerQuery {
+		perQueryRates[i] = calculateRateMetrics(int64(commands), 0, took)
 	}
-	count := totalsHistogram.TotalCount()
-	rate := calculateRateMetrics(count, 0, took)
-	perQueryRatesMap["Total"] = rate
-	return perQueryRatesMap
+	return perQueryRates, calculateRateMetrics(int64(totalCommands), 0, took)
 }
 
-func GetTotalsMap(queries []string, latenciesPerQuery []*hdrhistogram.Histogram, totalLatencies *hdrhistogram.Histogram, errorsPerQuery, totalNodesCreatedPerQuery, totalNodesDeletedPerQuery, totalLabelsAddedPerQuery, totalPropertiesSetPerQuery, totalRelationshipsCreatedPerQuery, totalRelationshipsDeletedPerQuery []uint64) map[string]interface{} {
-	totalsMap := map[string]interface{}{}
-
-	for i, query := range queries {
-		totalsMap[query] = generateTotalMap(uint64(latenciesPerQuery[i].TotalCount()), errorsPerQuery[i], totalNodesCreatedPerQuery[i], totalNodesDeletedPerQuery[i], totalLabelsAddedPerQuery[i], totalPropertiesSetPerQuery[i], totalRelationshipsCreatedPerQuery[i], totalRelationshipsDeletedPerQuery[i])
+func GetTotals(commandsPerQuery []uint64, errorsPerQuery, totalNodesCreatedPerQuery, totalNodesDeletedPerQuery, totalLabelsAddedPerQuery, totalPropertiesSetPerQuery, totalRelationshipsCreatedPerQuery, totalRelationshipsDeletedPerQuery, emptyResultsetsPerQuery, totalRowsPerQuery, totalReplyBytesPerQuery []uint64) ([]interface{}, interface{}) {
+	perQueryTotals := make([]interface{}, len(commandsPerQuery))
+	for i := range commandsPerQuery {
+		perQueryTotals[i] = generateTotalMap(commandsPerQuery[i], errorsPerQuery[i], totalNodesCreatedPerQuery[i], totalNodesDeletedPerQuery[i], totalLabelsAddedPerQuery[i], totalPropertiesSetPerQuery[i], totalRelationshipsCreatedPerQuery[i], totalRelationshipsDeletedPerQuery[i], emptyResultsetsPerQuery[i], totalRowsPerQuery[i], totalReplyBytesPerQuery[i])
 	}
-	totalsMap["Total"] = generateTotalMap(uint64(totalLatencies.TotalCount()), CountTotal(errorsPerQuery), CountTotal(totalNodesCreatedPerQuery), CountTotal(totalNodesDeletedPerQuery), CountTotal(totalLabelsAddedPerQuery), CountTotal(totalPropertiesSetPerQuery), CountTotal(totalRelationshipsCreatedPerQuery), CountTotal(totalRelationshipsDeletedPerQuery))
-	return totalsMap
+	total := generateTotalMap(CountTotal(commandsPerQuery), CountTotal(errorsPerQuery), CountTotal(totalNodesCreatedPerQuery), CountTotal(totalNodesDeletedPerQuery), CountTotal(totalLabelsAddedPerQuery), CountTotal(totalPropertiesSetPerQuery), CountTotal(totalRelationshipsCreatedPerQuery), CountTotal(totalRelationshipsDeletedPerQuery), CountTotal(emptyResultsetsPerQuery), CountTotal(totalRowsPerQuery), CountTotal(totalReplyBytesPerQuery))
+	return perQueryTotals, total
 }
 
 func CountTotal(slice []uint64) (res uint64) {
@@ -257,7 +306,7 @@ func CountTotal(slice []uint64) (res uint64) {
 	return
 }
 
-func generateTotalMap(IssuedQueries, Errors, NodesCreated, NodesDeleted, LabelsAdded, PropertiesSet, RelationshipsCreated, RelationshipsDeleted uint64) interface{} {
-	mp := map[string]uint64{"IssuedQueries": IssuedQueries, "Errors": Errors, "NodesCreated": NodesCreated, "NodesDeleted": NodesDeleted, "LabelsAdded": LabelsAdded, "PropertiesSet": PropertiesSet, "RelationshipsCreated": RelationshipsCreated, "RelationshipsDeleted": RelationshipsDeleted}
+func generateTotalMap(IssuedQueries, Errors, NodesCreated, NodesDeleted, LabelsAdded, PropertiesSet, RelationshipsCreated, RelationshipsDeleted, EmptyResultsets, Rows, ReplyBytes uint64) interface{} {
+	mp := map[string]uint64{"IssuedQueries": IssuedQueries, "Errors": Errors, "NodesCreated": NodesCreated, "NodesDeleted": NodesDeleted, "LabelsAdded": LabelsAdded, "PropertiesSet": PropertiesSet, "RelationshipsCreated": RelationshipsCreated, "RelationshipsDeleted": RelationshipsDeleted, "EmptyResultsets": EmptyResultsets, "Rows": Rows, "ReplyBytes": ReplyBytes}
 	return mp
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"sync/atomic"
+	"time"
+)
+
+// TickSample holds the stats of a single query ( or the "Total" ) for one CLI tick
+type TickSample struct {
+	QueryId                string
+	Query                  string
+	Rate                   float64
+	Errors                 uint64
+	ClientLatencies        map[string]float64
+	GraphInternalLatencies map[string]float64
+}
+
+// TimeSeriesExporter pushes the per tick stats to a RedisTimeSeries compatible endpoint and/or
+// to a FalkorDB graph. The target is never the database under test.
+type TimeSeriesExporter struct {
+	conn          *falkordb.FalkorDB
+	graph         *falkordb.Graph
+	timeSeries    bool
+	keyPrefix     string
+	benchmarkName string
+	version       int64
+}
+
+func NewTimeSeriesExporter(config *ExporterConfig, benchmarkName string, falkorDBVersion int64) *TimeSeriesExporter {
+	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+	graph, conn := getStandaloneConn(config.Graph, addr, config.Password, config.TlsCaCertFile, 5)
+	exporter := &TimeSeriesExporter{conn: conn, timeSeries: config.TimeSeries, keyPrefix: config.KeyPrefix, benchmarkName: benchmarkName, version: falkorDBVersion}
+	if config.Graph != "" {
+		exporter.graph = graph
+	}
+	fmt.Printf("Exporting per tick metrics to %s (timeseries: %t, graph: '%s')\n", addr, config.TimeSeries, config.Graph)
+	return exporter
+}
+
+func (e *TimeSeriesExporter) Close() {
+	e.conn.Conn.Close()
+}
+
+// Export writes the tick samples. Errors are reported but never stop the benchmark.
+func (e *TimeSeriesExporter) Export(timestamp time.Time, samples []TickSample) {
+	ts := timestamp.UnixMilli()
+	if e.timeSeries {
+		if err := e.exportTimeSeries(ts, samples); err != nil {
+			fmt.Printf("\nUnable to export tick to time-series store: %v\n", err)
+		}
+	}
+	if e.graph != nil {
+		if err := e.exportGraph(ts, samples); err != nil {
+			fmt.Printf("\nUnable to export tick to graph '%s': %v\n", e.graph.Id, err)
+		}
+	}
+}
+
+func (e *TimeSeriesExporter) exportTimeSeries(ts int64, samples []TickSample) error {
+	ctx := context.Background()
+	pipe := e.conn.Conn.Pipeline()
+	for _, sample := range samples {
+		for metric, value := range tickSampleMetrics(sample) {
+			key := fmt.Sprintf("%s:%s:%s:%s", e.keyPrefix, e.benchmarkName, sample.QueryId, metric)
+			pipe.Do(ctx, "TS.ADD", key, ts, value, "ON_DUPLICATE", "LAST", "LABELS",
+				"benchmark", e.benchmarkName, "query_id", sample.QueryId, "query", sample.Query, "version", e.version, "metric", metric)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (e *TimeSeriesExporter) exportGraph(ts int64, samples []TickSample) error {
+	ticks := make([]interface{}, 0, len(samples))
+	for _, sample := range samples {
+		tick := map[string]interface{}{"benchmark": e.benchmarkName, "query_id": sample.QueryId, "query": sample.Query, "version": e.version, "timestamp": ts}
+		for metric, value := range tickSampleMetrics(sample) {
+			tick[metric] = value
+		}
+		ticks = append(ticks, tick)
+	}
+	_, err := e.graph.Query("UNWIND $ticks AS t CREATE (n:BenchmarkTick) SET n = t", map[string]interface{}{"ticks": ticks}, nil)
+	return err
+}
+
+// ExportServerStats writes a server stats sample, under the "server" query id. Errors are reported but never stop the benchmark.
+func (e *TimeSeriesExporter) ExportServerStats(sample ServerStatsSample) {
+	ctx := context.Background()
+	metrics := serverStatsMetrics(sample)
+	if e.timeSeries {
+		pipe := e.conn.Conn.Pipeline()
+		for metric, value := range metrics {
+			key := fmt.Sprintf("%s:%s:server:%s", e.keyPrefix, e.benchmarkName, metric)
+			pipe.Do(ctx, "TS.ADD", key, sample.TimestampMillis, value, "ON_DUPLICATE", "LAST", "LABELS",
+				"benchmark", e.benchmarkName, "query_id", "server", "version", e.version, "metric", metric)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			fmt.Printf("\nUnable to export server stats to time-series store: %v\n", err)
+		}
+	}
+	if e.graph != nil {
+		stats := map[string]interface{}{"benchmark": e.benchmarkName, "version": e.version, "timestamp": sample.TimestampMillis}
+		for metric, value := range metrics {
+			stats[metric] = value
+		}
+		if _, err := e.graph.Query("CREATE (n:BenchmarkServerSample) SET n = $stats", map[string]interface{}{"stats": stats}, nil); err != nil {
+			fmt.Printf("\nUnable to export server stats to graph '%s': %v\n", e.graph.Id, err)
+		}
+	}
+}
+
+func tickSampleMetrics(sample TickSample) map[string]interface{} {
+	metrics := map[string]interface{}{"rate": sample.Rate, "errors": int64(sample.Errors)}
+	for quantile, value := range sample.ClientLatencies {
+		metrics["client_"+quantile] = value
+	}
+	for quantile, value := range sample.GraphInternalLatencies {
+		metrics["internal_"+quantile] = value
+	}
+	return metrics
+}
+
+// collectTickSamples must be called before the instant histograms are reset.
+// prevCommandsPerQuery and prevErrorsPerQuery are updated in place with the current counts.
+func collectTickSamples(queryIds []string, queryLabels []string, took time.Duration, prevCommandsPerQuery []uint64, prevErrorsPerQuery []uint64, commands uint64, errors uint64) []TickSample {
+	samples := make([]TickSample, 0, len(queryIds)+1)
+	instantHistogramsResetMutex.Lock()
+	defer instantHistogramsResetMutex.Unlock()
+	for i, queryId := range queryIds {
+		queryCommands := atomic.LoadUint64(&totalCommandsPerQuery[i])
+		queryErrors := atomic.LoadUint64(&errorsPerQuery[i])
+		samples = append(samples, newTickSample(queryId, queryLabels[i], took, queryCommands-prevCommandsPerQuery[i], queryErrors-prevErrorsPerQuery[i], clientSidePerQueryInstantLatencies[i], serverSidePerQueryGraphInternalTimeInstantLatencies[i]))
+		prevCommandsPerQuery[i] = queryCommands
+		prevErrorsPerQuery[i] = queryErrors
+	}
+	samples = append(samples, newTickSample("Total", "Total", took, commands, errors, clientSideAllQueriesInstantLatencies, serverSideAllQueriesGraphInternalTimeInstantLatencies))
+	return samples
+}
+
+func newTickSample(queryId, query string, took time.Duration, commands uint64, errors uint64, clientHistogram, internalHistogram *hdrhistogram.Histogram) TickSample {
+	return TickSample{
+		QueryId:                queryId,
+		Query:                  query,
+		Rate:                   calculateRateMetrics(int64(commands), 0, took),
+		Errors:                 errors,
+		ClientLatencies:        tickQuantiles(clientHistogram),
+		GraphInternalLatencies: tickQuantiles(internalHistogram),
+	}
+}
+
+func tickQuantiles(histogram *hdrhistogram.Histogram) map[string]float64 {
+	return map[string]float64{
+		"p50": float64(histogram.ValueAtQuantile(50.0)) / 1000.0,
+		"p95": float64(histogram.ValueAtQuantile(95.0)) / 1000.0,
+		"p99": float64(histogram.ValueAtQuantile(99.0)) / 1000.0,
+	}
+}
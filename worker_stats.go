@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// how often the stats recorded by the workers are merged into the global stats, for the live CLI and metrics
+const statsMergeInterval = 100 * time.Millisecond
+
+// valueCounts is a histogram of exact values, recorded by a single worker. It only holds the distinct values of an
+// interval, so it stays small whatever the range of the values, and merges into the hdr histograms without any loss.
+type valueCounts map[int64]int64
+
+func (c valueCounts) record(value int64) {
+	c[value]++
+}
+
+func (c valueCounts) mergeInto(histograms ...*hdrhistogram.Histogram) {
+	for value, count := range c {
+		for _, histogram := range histograms {
+			histogram.RecordValues(value, count)
+		}
+	}
+}
+
+// workerError is an error kept with its message, for the error class samples
+type workerError struct {
+	class           string
+	message         string
+	timestampMillis int64
+}
+
+// workerQueryStats holds the histograms and counters a worker recorded for a single query during an interval
+type workerQueryStats struct {
+	commands          uint64
+	errors            uint64
+	expectedErrors    uint64
+	failedRequests    uint64
+	retryAttempts     uint64
+	retryErrors       uint64
+	recoveredRequests uint64
+
+	emptyResultsets  uint64
+	cachedExecutions uint64
+	rows             uint64
+	replyBytes       uint64
+	validated        uint64
+
+	nodesCreated         uint64
+	nodesDeleted         uint64
+	labelsAdded          uint64
+	propertiesSet        uint64
+	relationshipsCreated uint64
+	relationshipsDeleted uint64
+
+	clientLatencies                valueCounts
+	graphInternalLatencies         valueCounts
+	failedLatencies                valueCounts
+	retryLatencies                 valueCounts
+	cachedClientLatencies          valueCounts
+	cachedGraphInternalLatencies   valueCounts
+	uncachedClientLatencies        valueCounts
+	uncachedGraphInternalLatencies valueCounts
+	rowsPerRequest                 valueCounts
+	replyBytesPerRequest           valueCounts
+
+	errorSamples []workerError
+	violations   []string
+}
+
+func newWorkerQueryStats() *workerQueryStats {
+	return &workerQueryStats{
+		clientLatencies: valueCounts{}, graphInternalLatencies: valueCounts{}, failedLatencies: valueCounts{}, retryLatencies: valueCounts{},
+		cachedClientLatencies: valueCounts{}, cachedGraphInternalLatencies: valueCounts{}, uncachedClientLatencies: valueCounts{}, uncachedGraphInternalLatencies: valueCounts{},
+		rowsPerRequest: valueCounts{}, replyBytesPerRequest: valueCounts{},
+	}
+}
+
+// add accounts a single datapoint. Retries are reported apart from the first attempts, and don't count as issued
+// commands. Failed requests have no internal execution time and are kept apart from the successful ones.
+func (s *workerQueryStats) add(dp GraphQueryDatapoint) {
+	if dp.RequestFailed {
+		s.failedRequests++
+	}
+	if dp.Retry {
+		s.retryLatencies.record(dp.ClientDurationMicros)
+		s.retryAttempts++
+		if dp.Error {
+			s.retryErrors++
+			return
+		}
+		s.recoveredRequests++
+		if dp.ExpectedError {
+			s.expectedErrors++
+			return
+		}
+		s.addResult(dp)
+		return
+	}
+	s.commands++
+	if dp.Error {
+		s.failedLatencies.record(dp.ClientDurationMicros)
+		s.errors++
+		s.errorSamples = append(s.errorSamples, workerError{class: dp.ErrorClass, message: dp.ErrorMessage, timestampMillis: dp.ErrorTimestampMillis})
+		return
+	}
+	s.clientLatencies.record(dp.ClientDurationMicros)
+	// expected errors count as a success, but have no internal execution time either
+	if dp.ExpectedError {
+		s.expectedErrors++
+		return
+	}
+	s.graphInternalLatencies.record(dp.GraphInternalDurationMicros)
+	s.addResult(dp)
+}
+
+// addResult accounts the result of a successful request
+func (s *workerQueryStats) addResult(dp GraphQueryDatapoint) {
+	s.nodesCreated += dp.NodesCreated
+	s.nodesDeleted += dp.NodesDeleted
+	s.labelsAdded += dp.LabelsAdded
+	s.propertiesSet += dp.PropertiesSet
+	s.relationshipsCreated += dp.RelationshipsCreated
+	s.relationshipsDeleted += dp.RelationshipsDeleted
+
+	s.rows += dp.Rows
+	s.replyBytes += dp.ReplyBytes
+	if dp.Empty {
+		s.emptyResultsets++
+	}
+	s.rowsPerRequest.record(int64(dp.Rows))
+	s.replyBytesPerRequest.record(int64(dp.ReplyBytes))
+
+	if dp.CachedExecution {
+		s.cachedExecutions++
+		s.cachedClientLatencies.record(dp.ClientDurationMicros)
+		s.cachedGraphInternalLatencies.record(dp.GraphInternalDurationMicros)
+	} else {
+		s.uncachedClientLatencies.record(dp.ClientDurationMicros)
+		s.uncachedGraphInternalLatencies.record(dp.GraphInternalDurationMicros)
+	}
+
+	if dp.Validated {
+		s.validated++
+		if dp.ValidationViolation != "" {
+			s.violations = append(s.violations, dp.ValidationViolation)
+		}
+	}
+}
+
+// reset empties the stats, keeping the memory of the histograms for the next interval
+func (s *workerQueryStats) reset() {
+	*s = workerQueryStats{
+		clientLatencies: s.clientLatencies, graphInternalLatencies: s.graphInternalLatencies, failedLatencies: s.failedLatencies, retryLatencies: s.retryLatencies,
+		cachedClientLatencies: s.cachedClientLatencies, cachedGraphInternalLatencies: s.cachedGraphInternalLatencies, uncachedClientLatencies: s.uncachedClientLatencies, uncachedGraphInternalLatencies: s.uncachedGraphInternalLatencies,
+		rowsPerRequest: s.rowsPerRequest, replyBytesPerRequest: s.replyBytesPerRequest,
+		errorSamples: s.errorSamples[:0], violations: s.violations[:0],
+	}
+	for _, counts := range []valueCounts{s.clientLatencies, s.graphInternalLatencies, s.failedLatencies, s.retryLatencies,
+		s.cachedClientLatencies, s.cachedGraphInternalLatencies, s.uncachedClientLatencies, s.uncachedGraphInternalLatencies,
+		s.rowsPerRequest, s.replyBytesPerRequest} {
+		clear(counts)
+	}
+}
+
+// workerInterval holds the stats of every query recorded by a worker since the last merge
+type workerInterval struct {
+	queries []*workerQueryStats
+	// time spent by the worker recording its datapoints
+	recordNanos uint64
+}
+
+func newWorkerInterval(queries int) *workerInterval {
+	interval := &workerInterval{queries: make([]*workerQueryStats, queries)}
+	for i := range interval.queries {
+		interval.queries[i] = newWorkerQueryStats()
+	}
+	return interval
+}
+
+// workerStats holds the stats of a single worker. The worker records into the active interval without any lock, and
+// the merger swaps it with the spare one before merging it: epoch is odd while the worker is recording, so that the
+// merger can wait for a record that may still be using the interval it swapped out.
+type workerStats struct {
+	active atomic.Pointer[workerInterval]
+	epoch  atomic.Uint64
+	// only used by the merger
+	spare *workerInterval
+}
+
+func newWorkerStats(queries int) *workerStats {
+	stats := &workerStats{spare: newWorkerInterval(queries)}
+	stats.active.Store(newWorkerInterval(queries))
+	return stats
+}
+
+func (s *workerStats) record(dp GraphQueryDatapoint) {
+	startT := time.Now()
+	s.epoch.Add(1)
+	interval := s.active.Load()
+	interval.queries[dp.CmdPos].add(dp)
+	interval.recordNanos += uint64(time.Since(startT).Nanoseconds())
+	s.epoch.Add(1)
+}
+
+// swap makes the spare interval the active one, and returns the previous one once the worker stopped recording into it
+func (s *workerStats) swap() *workerInterval {
+	previous := s.active.Swap(s.spare)
+	if epoch := s.epoch.Load(); epoch%2 == 1 {
+		for s.epoch.Load() == epoch {
+			runtime.Gosched()
+		}
+	}
+	s.spare = previous
+	return previous
+}
+
+// statsMerger merges the stats of the workers into the global stats, one worker at a time
+type statsMerger struct {
+	workers       []*workerStats
+	instantMutex  *sync.Mutex
+	errorPolicies []queryErrorPolicy
+	queryLabels   []string
+	stopper       *runStopper
+}
+
+func newStatsMerger(clients uint64, instantMutex *sync.Mutex, errorPolicies []queryErrorPolicy, queryLabels []string, stopper *runStopper) *statsMerger {
+	merger := &statsMerger{instantMutex: instantMutex, errorPolicies: errorPolicies, queryLabels: queryLabels, stopper: stopper}
+	merger.workers = make([]*workerStats, clients)
+	for i := range merger.workers {
+		merger.workers[i] = newWorkerStats(len(queryLabels))
+	}
+	return merger
+}
+
+// merge adds the stats of an interval to the global stats, and empties it
+func (m *statsMerger) merge(interval *workerInterval) {
+	// Only needs to be atomic due to the client stats sampler
+	atomic.AddUint64(&datapointsRecordBlockedNanos, interval.recordNanos)
+	interval.recordNanos = 0
+	for cmdPos, stats := range interval.queries {
+		m.instantMutex.Lock()
+		stats.clientLatencies.mergeInto(clientSidePerQueryOverallLatencies[cmdPos], clientSideAllQueriesOverallLatencies, clientSidePerQueryInstantLatencies[cmdPos], clientSideAllQueriesInstantLatencies)
+		stats.graphInternalLatencies.mergeInto(serverSidePerQueryGraphInternalTimeOverallLatencies[cmdPos], serverSideAllQueriesGraphInternalTimeOverallLatencies, serverSidePerQueryGraphInternalTimeInstantLatencies[cmdPos], serverSideAllQueriesGraphInternalTimeInstantLatencies)
+		stats.failedLatencies.mergeInto(clientSidePerQueryFailedLatencies[cmdPos], clientSideAllQueriesFailedLatencies)
+		if len(stats.retryLatencies) > 0 {
+			stats.retryLatencies.mergeInto(histogramOnFirstUse(&clientSidePerQueryRetryLatencies[cmdPos], newLatencyHistogram), histogramOnFirstUse(&clientSideAllQueriesRetryLatencies, newLatencyHistogram))
+		}
+		m.instantMutex.Unlock()
+
+		// Only needs to be atomic due to CLI print and metrics endpoint
+		atomic.AddUint64(&totalCommands, stats.commands)
+		atomic.AddUint64(&totalCommandsPerQuery[cmdPos], stats.commands)
+		atomic.AddUint64(&totalErrors, stats.errors)
+		atomic.AddUint64(&errorsPerQuery[cmdPos], stats.errors)
+		for _, e := range stats.errorSamples {
+			recordErrorClass(errorClassStatsPerQuery[cmdPos], e.class, e.message, e.timestampMillis, maxErrorSamples)
+			recordErrorClass(totalErrorClassStats, e.class, e.message, e.timestampMillis, maxErrorSamples)
+		}
+		expectedErrorsPerQuery[cmdPos] += stats.expectedErrors
+		retryAttemptsPerQuery[cmdPos] += stats.retryAttempts
+		retryErrorsPerQuery[cmdPos] += stats.retryErrors
+		recoveredRequestsPerQuery[cmdPos] += stats.recoveredRequests
+		recordWriteStats(cmdPos, stats)
+		recordResultSize(cmdPos, stats)
+		recordPlanCache(cmdPos, stats)
+		recordValidation(cmdPos, stats)
+
+		if stats.failedRequests > 0 {
+			failedRequests := atomic.AddUint64(&failedRequestsPerQuery[cmdPos], stats.failedRequests)
+			errorBudget := m.errorPolicies[cmdPos].errorBudget
+			if errorBudget > 0 && failedRequests > errorBudget {
+				m.stopper.Stop(fmt.Sprintf("error budget of %d failed requests exceeded for query %s", errorBudget, m.queryLabels[cmdPos]))
+			}
+		}
+		stats.reset()
+	}
+}
+
+func (m *statsMerger) mergeAll() {
+	for _, worker := range m.workers {
+		m.merge(worker.swap())
+	}
+}
+
+// run periodically merges the stats of every worker. Once done is closed, it does a final merge and returns.
+func (m *statsMerger) run(done <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(statsMergeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.mergeAll()
+		case <-done:
+			m.mergeAll()
+			return
+		}
+	}
+}
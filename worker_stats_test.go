@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/FalkorDB/falkordb-go"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_statsMerger(t *testing.T) {
+	createRequiredGlobalStructs(2)
+	totalCommands = 0
+	merger := newStatsMerger(2, &instantHistogramsResetMutex, []queryErrorPolicy{{}, {errorBudget: 1}}, []string{"q1", "q2"}, newRunStopper())
+	merger.workers[0].record(GraphQueryDatapoint{CmdPos: 0, ClientDurationMicros: 100, GraphInternalDurationMicros: 10, Rows: 3, CachedExecution: true})
+	merger.workers[0].record(GraphQueryDatapoint{CmdPos: 0, ClientDurationMicros: 100, GraphInternalDurationMicros: 20, Rows: 3, Validated: true, ValidationViolation: "min_rows"})
+	merger.workers[1].record(GraphQueryDatapoint{CmdPos: 1, ClientDurationMicros: 200, Error: true, RequestFailed: true, ErrorClass: errorClassOther, ErrorMessage: "a"})
+	merger.workers[1].record(GraphQueryDatapoint{CmdPos: 1, ClientDurationMicros: 300, Retry: true})
+	if totalCommands != 0 {
+		t.Errorf("the worker stats were merged before the merger ran, totalCommands = %d", totalCommands)
+	}
+	merger.mergeAll()
+	if totalCommands != 3 || totalCommandsPerQuery[1] != 1 || errorsPerQuery[1] != 1 || failedRequestsPerQuery[1] != 1 || recoveredRequestsPerQuery[1] != 1 {
+		t.Errorf("mergeAll() totals = %d %v %v %v %v", totalCommands, totalCommandsPerQuery, errorsPerQuery, failedRequestsPerQuery, recoveredRequestsPerQuery)
+	}
+	if clientSidePerQueryOverallLatencies[0].TotalCount() != 2 || clientSidePerQueryOverallLatencies[0].ValueAtQuantile(50) != 100 || serverSideAllQueriesGraphInternalTimeOverallLatencies.Max() != 20 {
+		t.Errorf("mergeAll() did not merge the worker histograms")
+	}
+	if cachedExecutionsPerQuery[0] != 1 || totalRowsPerQuery[0] != 6 || validationStatsPerQuery[0].Checked != 1 || validationStatsPerQuery[0].Violations != 1 {
+		t.Errorf("mergeAll() result totals = %v %v %+v", cachedExecutionsPerQuery, totalRowsPerQuery, validationStatsPerQuery[0])
+	}
+	if got := errorClassStatsPerQuery[1][errorClassOther]; got == nil || got.Count != 1 || clientSidePerQueryRetryLatencies[1].TotalCount() != 1 {
+		t.Errorf("mergeAll() errors = %+v", got)
+	}
+	if clientSidePerQueryRetryLatencies[0] != nil {
+		t.Errorf("mergeAll() created the retry latencies histogram of a query without retries")
+	}
+	if clientSidePerQueryCachedLatencies[1] != nil || clientSidePerQueryCachedLatencies[0].TotalCount() != 1 {
+		t.Errorf("mergeAll() created the cached latencies histogram of a query without cached executions")
+	}
+	merger.workers[1].record(GraphQueryDatapoint{CmdPos: 1, ClientDurationMicros: 200, Error: true, RequestFailed: true})
+	merger.mergeAll()
+	if !merger.stopper.Stopped() {
+		t.Errorf("mergeAll() did not stop the run once the error budget was exceeded")
+	}
+	merger.mergeAll()
+	if totalCommands != 4 {
+		t.Errorf("mergeAll() merged an interval twice, totalCommands = %d", totalCommands)
+	}
+}
+
+func Test_workerStats_swap(t *testing.T) {
+	createRequiredGlobalStructs(1)
+	totalCommands = 0
+	merger := newStatsMerger(1, &instantHistogramsResetMutex, []queryErrorPolicy{{}}, []string{"q"}, newRunStopper())
+	const datapoints = 100000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < datapoints; i++ {
+			merger.workers[0].record(GraphQueryDatapoint{ClientDurationMicros: int64(i % 1000)})
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		merger.mergeAll()
+	}
+	if totalCommands != datapoints || clientSideAllQueriesOverallLatencies.TotalCount() != datapoints {
+		t.Errorf("merged %d commands and %d latencies while the worker was recording, want %d", totalCommands, clientSideAllQueriesOverallLatencies.TotalCount(), datapoints)
+	}
+}
+
+// noopGraphServer replies to every GRAPH.* command with an empty result set, and to anything else with an error
+func noopGraphServer(b testing.TB) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	reply := "*1\r\n*2\r\n"
+	for _, stat := range []string{"Cached execution: 1", "Query internal execution time: 0.010000 milliseconds"} {
+		reply += fmt.Sprintf("$%d\r\n%s\r\n", len(stat), stat)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					command, err := readRespCommand(reader)
+					if err != nil {
+						return
+					}
+					if strings.HasPrefix(strings.ToUpper(command), "GRAPH.") {
+						_, err = io.WriteString(conn, reply)
+					} else {
+						_, err = io.WriteString(conn, "-ERR unknown command\r\n")
+					}
+					if err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+// readRespCommand reads a RESP array of bulk strings, and returns its first element
+func readRespCommand(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	args, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return "", err
+	}
+	command := ""
+	for i := 0; i < args; i++ {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return "", err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return "", err
+		}
+		arg := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, arg); err != nil {
+			return "", err
+		}
+		if i == 0 {
+			command = string(arg[:size])
+		}
+	}
+	return command, nil
+}
+
+// processDatapointsChannel is the baseline the worker stats replaced: a single processor reads every datapoint from a
+// shared channel, and records it into the global stats right away. recorded is closed once datapoints is drained.
+func processDatapointsChannel(datapoints <-chan GraphQueryDatapoint, merger *statsMerger) (recorded chan struct{}) {
+	recorded = make(chan struct{})
+	interval := newWorkerInterval(len(merger.queryLabels))
+	go func() {
+		defer close(recorded)
+		for dp := range datapoints {
+			interval.queries[dp.CmdPos].add(dp)
+			merger.merge(interval)
+		}
+	}()
+	return recorded
+}
+
+// Benchmark_harnessOverhead measures the ops/sec the harness achieves against a server that does no work, handing the
+// datapoints over through a single shared channel to a single processor, or recording them on the worker own stats
+func Benchmark_harnessOverhead(b *testing.B) {
+	listener := noopGraphServer(b)
+	defer listener.Close()
+	const clients = 64
+	for _, handoff := range []string{"datapoints-channel", "worker-stats"} {
+		b.Run(handoff, func(b *testing.B) {
+			createRequiredGlobalStructs(1)
+			totalCommands = 0
+			stopper := newRunStopper()
+			errorPolicies := []queryErrorPolicy{{}}
+			timeouts := []queryTimeouts{{}}
+			merger := newStatsMerger(clients, &instantHistogramsResetMutex, errorPolicies, []string{"q"}, stopper)
+			done := make(chan struct{})
+			processingWg := sync.WaitGroup{}
+			processingWg.Add(1)
+			go merger.run(done, &processingWg)
+			var record func(clientId int, dp GraphQueryDatapoint)
+			stopProcessing := func() { close(done) }
+			if handoff == "datapoints-channel" {
+				datapoints := make(chan GraphQueryDatapoint, clients)
+				recorded := processDatapointsChannel(datapoints, merger)
+				record = func(clientId int, dp GraphQueryDatapoint) { datapoints <- dp }
+				stopProcessing = func() {
+					close(datapoints)
+					<-recorded
+					close(done)
+				}
+			} else {
+				record = func(clientId int, dp GraphQueryDatapoint) { merger.workers[clientId].record(dp) }
+			}
+			graphs := make([]*falkordb.Graph, clients)
+			for i := range graphs {
+				graphs[i], _ = getStandaloneConn("bench", listener.Addr().String(), "", "", 5)
+			}
+			wg := sync.WaitGroup{}
+			b.ResetTimer()
+			for clientId := 0; clientId < clients; clientId++ {
+				wg.Add(1)
+				go func(clientId int) {
+					defer wg.Done()
+					for i := clientId; i < b.N; i += clients {
+						dp, _ := sendQuery(graphs[clientId], "RETURN 1", "RETURN 1", true, &errorPolicies[0], &timeouts[0], nil, nil, 0, false)
+						record(clientId, dp)
+					}
+				}(clientId)
+			}
+			wg.Wait()
+			stopProcessing()
+			processingWg.Wait()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+			if totalCommands != uint64(b.N) {
+				b.Fatalf("recorded %d commands, want %d", totalCommands, b.N)
+			}
+			for _, graph := range graphs {
+				graph.Conn.Close()
+			}
+		})
+	}
+}
+
+// Benchmark_datapointsHandoff isolates the cost of handing a datapoint over to the stats, without any network. Every
+// parallel goroutine is a client with its own worker stats, whatever GOMAXPROCS is.
+func Benchmark_datapointsHandoff(b *testing.B) {
+	const clients = 64
+	parallelism := (clients + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0)
+	goroutines := parallelism * runtime.GOMAXPROCS(0)
+	for _, handoff := range []string{"datapoints-channel", "worker-stats"} {
+		b.Run(handoff, func(b *testing.B) {
+			createRequiredGlobalStructs(1)
+			totalCommands = 0
+			merger := newStatsMerger(uint64(goroutines), &instantHistogramsResetMutex, []queryErrorPolicy{{}}, []string{"q"}, newRunStopper())
+			done := make(chan struct{})
+			processingWg := sync.WaitGroup{}
+			processingWg.Add(1)
+			go merger.run(done, &processingWg)
+			datapoints := make(chan GraphQueryDatapoint, clients)
+			recorded := processDatapointsChannel(datapoints, merger)
+			var next atomic.Int32
+			b.SetParallelism(parallelism)
+			b.RunParallel(func(pb *testing.PB) {
+				worker := merger.workers[next.Add(1)-1]
+				dp := GraphQueryDatapoint{ClientDurationMicros: 100, GraphInternalDurationMicros: 10}
+				for pb.Next() {
+					if handoff == "datapoints-channel" {
+						datapoints <- dp
+					} else {
+						worker.record(dp)
+					}
+				}
+			})
+			close(datapoints)
+			<-recorded
+			close(done)
+			processingWg.Wait()
+			if totalCommands != uint64(b.N) {
+				b.Fatalf("recorded %d commands, want %d", totalCommands, b.N)
+			}
+		})
+	}
+}
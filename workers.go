@@ -1,49 +1,68 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/FalkorDB/falkordb-go"
 	"golang.org/x/time/rate"
-	"log"
-	"math/rand"
-	"strings"
 	"sync"
 	"time"
 )
 
-func ingestionRoutine(rg *falkordb.Graph, continueOnError bool, cmdS []string, commandIsRO []bool, commandsCDF []float32, randomIntPadding, randomIntMax int64, numberSamples uint64, loop bool, verbose bool, wg *sync.WaitGroup, useLimiter bool, rateLimiter *rate.Limiter, statsChannel chan GraphQueryDatapoint, replacementEnabled bool, replacementArr []map[string]string, commandStartPos uint64, panicChannel chan bool) {
+func ingestionRoutine(rg *falkordb.Graph, continueOnError bool, cmdS []string, templates []*queryTemplate, commandIsRO []bool, commandsCDF []float32, errorPolicies []queryErrorPolicy, timeouts []queryTimeouts, validators []*resultValidator, randomIntPadding, randomIntMax int64, numberSamples uint64, loop bool, verbose bool, wg *sync.WaitGroup, useLimiter bool, rateLimiter *rate.Limiter, stats *workerStats, pickers []*termsPicker, querySources [][]int, stopper *runStopper) {
 	defer func() {
 		if r := recover(); r != nil {
-			panicChannel <- true
+			stopper.Stop(fmt.Sprintf("panic in worker routine: %v", r))
 		}
 		wg.Done()
 	}()
-	var replacementTerms map[string]string
-	for i := 0; uint64(i) < numberSamples || loop; i++ {
+	termRows := make([]*termRow, 0, len(pickers))
+	for i := 0; (uint64(i) < numberSamples || loop) && !stopper.Stopped(); i++ {
 		cmdPos := sample(commandsCDF)
-		termReplacementPos := commandStartPos + uint64(i)
-		if replacementEnabled {
-			replacementTerms = replacementArr[termReplacementPos]
+		termRows = termRows[:0]
+		for _, s := range querySources[cmdPos] {
+			termRows = append(termRows, pickers[s].pick())
 		}
-		sendCmdLogic(rg, cmdS[cmdPos], commandIsRO[cmdPos], randomIntPadding, randomIntMax, cmdPos, continueOnError, verbose, useLimiter, rateLimiter, statsChannel, replacementEnabled, replacementTerms)
+		sendCmdLogic(rg, cmdS[cmdPos], templates[cmdPos], commandIsRO[cmdPos], &errorPolicies[cmdPos], &timeouts[cmdPos], validators[cmdPos], randomIntPadding, randomIntMax, cmdPos, continueOnError, verbose, useLimiter, rateLimiter, stats, termRows, stopper)
 	}
 }
 
-func sendCmdLogic(graph *falkordb.Graph, query string, readOnly bool, randomIntPadding, randomIntMax int64, cmdPos int, continueOnError bool, verbose bool, useRateLimiter bool, rateLimiter *rate.Limiter, statsChannel chan GraphQueryDatapoint, replacementEnabled bool, replacementTerms map[string]string) {
+func sendCmdLogic(graph *falkordb.Graph, query string, template *queryTemplate, readOnly bool, errorPolicy *queryErrorPolicy, timeouts *queryTimeouts, validator *resultValidator, randomIntPadding, randomIntMax int64, cmdPos int, continueOnError bool, verbose bool, useRateLimiter bool, rateLimiter *rate.Limiter, stats *workerStats, termRows []*termRow, stopper *runStopper) {
 	if useRateLimiter {
 		r := rateLimiter.ReserveN(time.Now(), int(1))
 		time.Sleep(r.Delay())
 	}
-	var err error
-	var queryResult *falkordb.QueryResult
+	processedQuery := template.render(randomIntPadding, randomIntMax, termRows)
+	// retries of transient errors reuse the same processed query
+	for attempt := 0; ; attempt++ {
+		datapoint, err := sendQuery(graph, query, processedQuery, readOnly, errorPolicy, timeouts, validator, termRows, cmdPos, verbose)
+		datapoint.Retry = attempt > 0
+		retry := datapoint.Error && errorPolicy.shouldRetry(datapoint.ErrorClass, attempt)
+		// no retry is issued once the run is stopped, the error fails the request
+		retry = retry && !stopper.Stopped()
+		datapoint.RequestFailed = datapoint.Error && !retry
+		stats.record(datapoint)
+		if retry {
+			time.Sleep(errorPolicy.backoff(attempt))
+			continue
+		}
+		if datapoint.RequestFailed {
+			if continueOnError {
+				if verbose {
+					fmt.Printf("Received an error with the following query(s): %v, error: %v", query, err)
+				}
+			} else {
+				stopper.Stop(fmt.Sprintf("received an error with the following query(s): %v, error: %v", query, err))
+			}
+		}
+		return
+	}
+}
 
-	processedQuery := processQuery(query, randomIntPadding, randomIntMax, replacementEnabled, replacementTerms)
+// sendQuery issues a single attempt of the query and returns its datapoint
+func sendQuery(graph *falkordb.Graph, query string, processedQuery string, readOnly bool, errorPolicy *queryErrorPolicy, timeouts *queryTimeouts, validator *resultValidator, termRows []*termRow, cmdPos int, verbose bool) (GraphQueryDatapoint, error) {
 	startT := time.Now()
-	if readOnly {
-		queryResult, err = graph.ROQuery(processedQuery, map[string]interface{}{}, nil)
-	} else {
-		queryResult, err = graph.Query(processedQuery, map[string]interface{}{}, nil)
-	}
+	queryResult, replyBytes, err := executeQuery(graph, processedQuery, readOnly, timeouts)
 	endT := time.Now()
 
 	duration := endT.Sub(startT)
@@ -61,14 +80,18 @@ func sendCmdLogic(graph *falkordb.Graph, query string, readOnly bool, randomIntP
 		RelationshipsDeleted:        0,
 	}
 	if err != nil {
-		datapoint.Error = true
-		if continueOnError {
+		if errorPolicy.isExpected(err) {
+			// counts as a success, but without any internal execution time
+			datapoint.ExpectedError = true
 			if verbose {
-				fmt.Printf("Received an error with the following query(s): %v, error: %v", query, err)
+				fmt.Printf("Received an expected error with the following query(s): %v, error: %v\n", query, err)
 			}
-		} else {
-			log.Panicf("Received an error with the following query(s): %v, error: %v", query, err)
+			return datapoint, nil
 		}
+		datapoint.Error = true
+		datapoint.ErrorClass = classifyError(err)
+		datapoint.ErrorMessage = err.Error()
+		datapoint.ErrorTimestampMillis = endT.UnixMilli()
 	} else {
 		datapoint.GraphInternalDurationMicros = int64(queryResult.InternalExecutionTime() * 1000.0)
 		if verbose {
@@ -77,7 +100,18 @@ func sendCmdLogic(graph *falkordb.Graph, query string, readOnly bool, randomIntP
 			queryResult.PrettyPrint()
 			fmt.Printf("\n")
 		}
+		datapoint.ReplyBytes = replyBytes
+		// the rows are only built when the result is validated
+		if validator != nil && validator.sampled() {
+			columns, rows := resultSetRows(queryResult)
+			datapoint.Rows = uint64(len(rows))
+			datapoint.Validated = true
+			datapoint.ValidationViolation = validator.validate(columns, rows, mergedTerms(termRows))
+		} else {
+			datapoint.Rows = resultSetSize(queryResult)
+		}
 		datapoint.Empty = queryResult.Empty()
+		datapoint.CachedExecution = queryResult.CachedExecution() == 1
 		datapoint.NodesCreated = uint64(queryResult.NodesCreated())
 		datapoint.NodesDeleted = uint64(queryResult.NodesDeleted())
 		datapoint.LabelsAdded = uint64(queryResult.LabelsAdded())
@@ -85,18 +119,31 @@ func sendCmdLogic(graph *falkordb.Graph, query string, readOnly bool, randomIntP
 		datapoint.RelationshipsCreated = uint64(queryResult.RelationshipsCreated())
 		datapoint.RelationshipsDeleted = uint64(queryResult.RelationshipsDeleted())
 	}
-	statsChannel <- datapoint
+	return datapoint, err
 }
 
-func processQuery(query string, randomIntPadding int64, randomIntMax int64, replacementEnabled bool, replacementTerms map[string]string) string {
-	if replacementEnabled {
-		for placeholder, term := range replacementTerms {
-			query = strings.Replace(query, placeholder, term, -1)
-		}
+// executeQuery runs the query with its server side timeout, bounded by the client side deadline when one is set.
+// It also returns the approximate size of the reply, in bytes.
+func executeQuery(graph *falkordb.Graph, processedQuery string, readOnly bool, timeouts *queryTimeouts) (*falkordb.QueryResult, uint64, error) {
+	// the command is issued directly on the connection, given the client API takes no context and hides the raw reply
+	command := "GRAPH.QUERY"
+	if readOnly {
+		command = "GRAPH.RO_QUERY"
+	}
+	args := []interface{}{command, graph.Id, processedQuery, "--compact"}
+	if timeouts.server > 0 {
+		args = append(args, "timeout", timeouts.server)
 	}
-	for strings.Index(query, randIntPlaceholder) != -1 {
-		randIntString := fmt.Sprintf("%d", rand.Int63n(randomIntMax)+randomIntPadding)
-		query = strings.Replace(query, randIntPlaceholder, randIntString, 1)
+	ctx := context.Background()
+	if timeouts.client > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeouts.client)
+		defer cancel()
+	}
+	reply, err := graph.Conn.Do(ctx, args...).Result()
+	if err != nil {
+		return nil, 0, err
 	}
-	return query
+	queryResult, err := falkordb.QueryResultNew(graph, reply)
+	return queryResult, replySize(reply), err
 }
@@ -1,10 +1,25 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 )
 
+// processQuery parses and renders the query at once, with a single line of terms
+func processQuery(query string, randomIntPadding int64, randomIntMax int64, replacementEnabled bool, replacementTerms map[string]string) string {
+	var termPlaceholders []string
+	var termRows []*termRow
+	if replacementEnabled {
+		for placeholder := range replacementTerms {
+			termPlaceholders = append(termPlaceholders, placeholder)
+		}
+		termRows = []*termRow{{terms: replacementTerms}}
+	}
+	return newQueryTemplate(query, termPlaceholders).render(randomIntPadding, randomIntMax, termRows)
+}
+
 func Test_processQuery(t *testing.T) {
 	type args struct {
 		query            string
@@ -38,3 +53,95 @@ func Test_processQuery(t *testing.T) {
 		})
 	}
 }
+
+func Test_newQueryTemplate(t *testing.T) {
+	terms := []*termRow{{terms: map[string]string{"__id__": "7", "__id_2__": "8"}}, {terms: map[string]string{"__name__": "a"}}}
+	tests := []struct {
+		name             string
+		query            string
+		termPlaceholders []string
+		wantSlots        int
+		want             string
+	}{
+		{"no-placeholders", "MATCH (n) RETURN n", []string{"__id__"}, 0, "MATCH (n) RETURN n"},
+		{"placeholders-at-both-ends", "__id__ RETURN __name__", []string{"__id__", "__name__"}, 2, "7 RETURN a"},
+		{"adjacent-placeholders", "__id____name__", []string{"__id__", "__name__"}, 2, "7a"},
+		{"longest-placeholder-wins", "RETURN __id_2__, __id__", []string{"__id__", "__id_2__"}, 2, "RETURN 8, 7"},
+		{"repeated-placeholder", "RETURN __id__ + __id__", []string{"__id__"}, 2, "RETURN 7 + 7"},
+		{"rand-int", "RETURN __rand_int__, __id__", []string{"__id__"}, 2, "RETURN 0, 7"},
+		{"unknown-term-left-as-is", "RETURN __other__", []string{"__other__"}, 1, "RETURN __other__"},
+		{"empty-placeholder-ignored", "RETURN 1", []string{""}, 0, "RETURN 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := newQueryTemplate(tt.query, tt.termPlaceholders)
+			if len(template.slots) != tt.wantSlots || len(template.literals) != tt.wantSlots+1 {
+				t.Errorf("newQueryTemplate() has %d slots and %d literals, want %d slots", len(template.slots), len(template.literals), tt.wantSlots)
+			}
+			if got := template.render(0, 1, terms); got != tt.want {
+				t.Errorf("render() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_queryTemplate_render_params(t *testing.T) {
+	terms := []*termRow{{terms: map[string]string{"__id__": "7"}, params: " id=7"}, {params: " name=\"a\""}}
+	tests := []struct {
+		name     string
+		query    string
+		termRows []*termRow
+		want     string
+	}{
+		{"no-header", "MATCH (n {id: $id}) RETURN n", terms, "CYPHER id=7 name=\"a\" MATCH (n {id: $id}) RETURN n"},
+		{"existing-header", "  cypher limit=3 MATCH (n {id: $id}) RETURN n LIMIT $limit", terms, "CYPHER id=7 name=\"a\" limit=3 MATCH (n {id: $id}) RETURN n LIMIT $limit"},
+		{"existing-header-without-params", "CYPHER limit=3 RETURN __id__", nil, "CYPHER limit=3 RETURN __id__"},
+		{"cypher-prefixed-identifier", "CYPHERS", terms[1:], "CYPHER name=\"a\" CYPHERS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newQueryTemplate(tt.query, []string{"__id__"}).render(0, 1, tt.termRows); got != tt.want {
+				t.Errorf("render() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// legacyProcessQuery is the former per request query rewriting, kept to benchmark the templates against it
+func legacyProcessQuery(query string, randomIntPadding int64, randomIntMax int64, replacementEnabled bool, replacementTerms map[string]string) string {
+	if replacementEnabled {
+		for placeholder, term := range replacementTerms {
+			query = strings.Replace(query, placeholder, term, -1)
+		}
+	}
+	for strings.Index(query, randIntPlaceholder) != -1 {
+		randIntString := fmt.Sprintf("%d", rand.Int63n(randomIntMax)+randomIntPadding)
+		query = strings.Replace(query, randIntPlaceholder, randIntString, 1)
+	}
+	return query
+}
+
+func Benchmark_processQuery(b *testing.B) {
+	terms := map[string]string{"__Entity__": "fbfa03a5-762b-4d32-be97-f19f3f3dda72", "__Label__": "Person", "__Limit__": "10"}
+	termRows := []*termRow{{terms: terms}}
+	queries := map[string]string{
+		"rand-int": "MATCH (n)-[:KNOWS]->(m) WHERE ID(n) = __rand_int__ AND ID(m) <> __rand_int__ RETURN m",
+		"terms":    "CYPHER entityUid='__Entity__' MATCH(entity:__Label__{entityUid:$entityUid}) RETURN entity LIMIT __Limit__",
+	}
+	for name, query := range queries {
+		b.Run(name+"/legacy", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				legacyProcessQuery(query, 1, 1000000, true, terms)
+			}
+		})
+		b.Run(name+"/template", func(b *testing.B) {
+			template := newQueryTemplate(query, []string{"__Entity__", "__Label__", "__Limit__"})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				template.render(1, 1000000, termRows)
+			}
+		})
+	}
+}
@@ -1,31 +1,231 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 )
 
 type Query struct {
+	Name  string  `yaml:"name,omitempty"`
 	Query string  `yaml:"query"`
 	Ratio float64 `yaml:"ratio"`
+	// Regular expressions of errors that count as a success
+	ExpectedErrors []string `yaml:"expected_errors,flow,omitempty"`
+	// Number of retries of transient ( timeout and connection ) errors
+	Retries            int `yaml:"retries,omitempty"`
+	RetryBackoffMillis int `yaml:"retry_backoff_ms,omitempty"`
+	// Maximum number of failed requests before the run is stopped. 0 means unlimited
+	ErrorBudget uint64 `yaml:"error_budget,omitempty"`
+	// Query timeout passed to FalkorDB, and client side deadline of every attempt, in milliseconds. 0 means none
+	Timeout       int `yaml:"timeout,omitempty"`
+	ClientTimeout int `yaml:"client_timeout,omitempty"`
+	// What a correct result looks like
+	Expect *QueryExpectations `yaml:"expect,omitempty"`
+}
+
+// QueryExpectations describes the result set of a query. Results not meeting them are counted as violations.
+type QueryExpectations struct {
+	Rows     *int     `yaml:"rows,omitempty"`
+	MinRows  *int     `yaml:"min_rows,omitempty"`
+	MaxRows  *int     `yaml:"max_rows,omitempty"`
+	Columns  []string `yaml:"columns,flow,omitempty"`
+	NonEmpty bool     `yaml:"non_empty,omitempty"`
+	// Expected values of the first row, for a given set of replacement terms
+	Values []ExpectedValues `yaml:"values,omitempty"`
+	// Fraction of the results that are validated, default is 1 ( all of them )
+	SampleRate float64 `yaml:"sample_rate,omitempty"`
+}
+
+type ExpectedValues struct {
+	// Placeholder to term, the values are only checked when the query was issued with these terms
+	When     map[string]string `yaml:"when"`
+	FirstRow []string          `yaml:"first_row,flow"`
+}
+
+// TermSourceConfig describes a file or a query of replacement terms, and how the clients pick its lines
+type TermSourceConfig struct {
+	Name string `yaml:"name"`
+	// Relative paths are relative to the YAML file
+	File string `yaml:"file,omitempty"`
+	// Read-only query run on the benchmark graph once the dataset is loaded, instead of a file
+	Cypher string `yaml:"cypher,omitempty"`
+	// Reservoir sample size of the query rows. Default is 0, keeping every row
+	SampleSize int `yaml:"sample_size,omitempty"`
+	// csv, tsv or jsonl. Default is inferred from the file extension, csv otherwise
+	Format string `yaml:"format,omitempty"`
+	// seq, rand, zipf or partitioned. Default is seq
+	Mode string `yaml:"mode,omitempty"`
+	// Exponent of the zipf distribution, must be bigger than 1. Default is 1.1
+	ZipfS float64 `yaml:"zipf_s,omitempty"`
+	// Names of the queries using this source. Default is all the queries
+	Queries []string `yaml:"queries,flow,omitempty"`
+	// Default is every column of the file or query, as a string placeholder named after the column
+	Columns []TermColumn `yaml:"columns,omitempty"`
+}
+
+type TermColumn struct {
+	// CSV or TSV header, JSON Lines key, or query column
+	Name string `yaml:"name"`
+	// Replaced by the term in the query text
+	Placeholder string `yaml:"placeholder,omitempty"`
+	// Passed as a Cypher param of that name
+	Param string `yaml:"param,omitempty"`
+	// string, int, float or bool. Default is string
+	Type string `yaml:"type,omitempty"`
+}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// DatasetConfig is either the path or URL of an RDB file, a dataset of the registry, or a description of CSV files
+type DatasetConfig struct {
+	// Local path, or http(s) URL, of an RDB file loaded when the database starts
+	Path string `yaml:"path,omitempty"`
+	// Dataset of the registry, as name@version, or as a name with its version below
+	Name    string `yaml:"name,omitempty"`
+	Version string `yaml:"version,omitempty"`
+	// Expected checksum of the file, before it is decompressed. Downloads are cached by checksum, or by URL without one
+	Sha256 string `yaml:"sha256,omitempty"`
+	// CSV files loaded once the database is started
+	Csv *CsvDatasetConfig `yaml:"csv,omitempty"`
+	// Expected counts of an RDB dataset, verified once it is loaded. Registry datasets get them from the registry
+	Nodes *int64 `yaml:"nodes,omitempty"`
+	Edges *int64 `yaml:"edges,omitempty"`
+
+	// Identity of a registry or RDB dataset, recorded in the results
+	Identity *DatasetIdentity `yaml:"-"`
+}
+
+// UnmarshalYAML accepts a plain string as the path of an RDB file, as well as a mapping. A plain string is only taken
+// as a registry name@version once the registry is known, see registryDatasetReference.
+func (d *DatasetConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&d.Path)
+	}
+	type plain DatasetConfig
+	return value.Decode((*plain)(d))
+}
+
+type CsvDatasetConfig struct {
+	// Rows of every UNWIND write query. Default is 1000
+	BatchSize int `yaml:"batch_size,omitempty"`
+	// Number of connections issuing the write queries. Default is 4
+	Concurrency   int                   `yaml:"concurrency,omitempty"`
+	Nodes         []CsvNodesFile        `yaml:"nodes"`
+	Relationships []CsvRelationshipFile `yaml:"relationships,omitempty"`
+	// Created once the graph is loaded
+	Indices []GenerateIndex `yaml:"indices,omitempty"`
+}
+
+type CsvNodesFile struct {
+	Label string `yaml:"label"`
+	// Relative paths are relative to the YAML file
+	File string `yaml:"file"`
+	// Column identifying the nodes in the relationship files, loaded as a property as well. Default is id
+	Id string `yaml:"id,omitempty"`
+	// Column to string, int, float or bool. Columns not listed are strings
+	Types map[string]string `yaml:"types,omitempty"`
+}
+
+type CsvRelationshipFile struct {
+	Type string `yaml:"type"`
+	File string `yaml:"file"`
+	// Labels of the source and destination nodes
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// Columns of the source and destination node ids. Default is src and dst
+	FromColumn string            `yaml:"from_column,omitempty"`
+	ToColumn   string            `yaml:"to_column,omitempty"`
+	Types      map[string]string `yaml:"types,omitempty"`
+}
+
+// GenerateConfig describes a synthetic graph, built before the benchmark instead of loading a dataset
+type GenerateConfig struct {
+	// Rows of every UNWIND write query. Default is 1000
+	BatchSize     int                    `yaml:"batch_size,omitempty"`
+	Nodes         []GenerateNodes        `yaml:"nodes"`
+	Relationships []GenerateRelationship `yaml:"relationships,omitempty"`
+	// Created once the graph is loaded
+	Indices []GenerateIndex `yaml:"indices,omitempty"`
+}
+
+type GenerateNodes struct {
+	Label      string             `yaml:"label"`
+	Count      int                `yaml:"count"`
+	Properties []GenerateProperty `yaml:"properties,omitempty"`
+}
+
+type GenerateRelationship struct {
+	Type string `yaml:"type"`
+	// Labels of the source and destination nodes
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// uniform, power_law or erdos_renyi. Default is uniform
+	Distribution string `yaml:"distribution,omitempty"`
+	// Average out degree of the source nodes
+	Degree float64 `yaml:"degree,omitempty"`
+	// Exponent of the power_law out degrees, must be bigger than 2. Default is 2.5
+	Exponent float64 `yaml:"exponent,omitempty"`
+	// Probability of every pair of nodes to be connected by erdos_renyi. Default is degree / destination nodes
+	Probability float64            `yaml:"probability,omitempty"`
+	Properties  []GenerateProperty `yaml:"properties,omitempty"`
+}
+
+type GenerateProperty struct {
+	Name string `yaml:"name"`
+	// id ( the position of the entity, from 0 ), int, float, string or bool. Default is id
+	Type string `yaml:"type,omitempty"`
+	// Bounds of int and float values. Default is [0, 1000000]
+	Min float64 `yaml:"min,omitempty"`
+	Max float64 `yaml:"max,omitempty"`
+	// Length of random string values. Default is 8
+	Length int `yaml:"length,omitempty"`
+	// String values picked at random, instead of random strings
+	Values []string `yaml:"values,flow,omitempty"`
+}
+
+// GenerateIndex is a range index on either a node label or a relationship type
+type GenerateIndex struct {
+	Label      string   `yaml:"label,omitempty"`
+	Type       string   `yaml:"type,omitempty"`
+	Properties []string `yaml:"properties,flow"`
+}
+
+// ExporterConfig describes where the per tick metrics are pushed to. It is separate from the database under test.
+type ExporterConfig struct {
+	Host          string `yaml:"host,omitempty"`
+	Port          int    `yaml:"port,omitempty"`
+	Password      string `yaml:"password,omitempty"`
+	TlsCaCertFile string `yaml:"tls_ca_cert_file,omitempty"`
+	TimeSeries    bool   `yaml:"timeseries,omitempty"`
+	KeyPrefix     string `yaml:"key_prefix,omitempty"`
+	Graph         string `yaml:"graph,omitempty"`
 }
 
 type YamlConfig struct {
-	Name            *string `yaml:"name"`
-	Description     string  `yaml:"description,omitempty"`
-	DockerImage     string  `yaml:"docker_image,omitempty"`
-	DatabaseModule  string  `yaml:"database_module,omitempty"`
-	ContinueOnError bool    `yaml:"continue_on_error,omitempty"`
+	Name            *string         `yaml:"name"`
+	Description     string          `yaml:"description,omitempty"`
+	DockerImage     string          `yaml:"docker_image,omitempty"`
+	DatabaseModule  string          `yaml:"database_module,omitempty"`
+	ContinueOnError bool            `yaml:"continue_on_error,omitempty"`
+	Exporter        *ExporterConfig `yaml:"exporter,omitempty"`
 	DBConfig        struct {
-		Host                   string     `yaml:"host,omitempty"`
-		Port                   int        `yaml:"port,omitempty"`
-		InitCommands           [][]string `yaml:"init_commands,flow,omitempty"`
-		Dataset                *string    `yaml:"dataset,omitempty"`
-		DatasetLoadTimeoutSecs int        `yaml:"dataset_load_timeout_secs,omitempty"`
-		Password               string     `yaml:"password,omitempty"`
-		TlsCaCertFile          string     `yaml:"tls_ca_cert_file,omitempty"`
+		Host                   string          `yaml:"host,omitempty"`
+		Port                   int             `yaml:"port,omitempty"`
+		InitCommands           [][]string      `yaml:"init_commands,flow,omitempty"`
+		Dataset                *DatasetConfig  `yaml:"dataset,omitempty"`
+		Generate               *GenerateConfig `yaml:"generate,omitempty"`
+		DatasetLoadTimeoutSecs int             `yaml:"dataset_load_timeout_secs,omitempty"`
+		Password               string          `yaml:"password,omitempty"`
+		TlsCaCertFile          string          `yaml:"tls_ca_cert_file,omitempty"`
+		// YAML or JSON file of named datasets, relative to the YAML file
+		DatasetRegistry string `yaml:"dataset_registry,omitempty"`
 	} `yaml:"db_config"`
 	Parameters struct {
 		Graph             string  `yaml:"graph"`
@@ -37,6 +237,8 @@ type YamlConfig struct {
 		RandomSeed        *int64  `yaml:"random_seed,omitempty"`
 		Queries           []Query `yaml:"queries,flow,omitempty"`
 		RoQueries         []Query `yaml:"ro_queries,flow,omitempty"`
+		// Replacement terms of the queries
+		TermSources []TermSourceConfig `yaml:"term_sources,omitempty"`
 	} `yaml:"parameters"`
 }
 
@@ -68,6 +270,123 @@ func parseYaml(yamlFile string) (yamlConfig YamlConfig, err error) {
 		return
 	}
 
+	queryNames := map[string]bool{}
+	for _, query := range orderedQueries(yamlConfig.Parameters.Queries, yamlConfig.Parameters.RoQueries) {
+		for _, pattern := range query.ExpectedErrors {
+			if _, err = regexp.Compile(pattern); err != nil {
+				err = fmt.Errorf("invalid expected error pattern '%s': %v", pattern, err)
+				return
+			}
+		}
+		if query.Retries < 0 {
+			err = fmt.Errorf("retries can't be negative, query '%s'", query.Query)
+			return
+		}
+		if query.Timeout < 0 || query.ClientTimeout < 0 {
+			err = fmt.Errorf("timeout and client_timeout can't be negative, query '%s'", query.Query)
+			return
+		}
+		if expect := query.Expect; expect != nil {
+			if expect.SampleRate < 0 || expect.SampleRate > 1 {
+				err = fmt.Errorf("expect sample_rate must be between 0 and 1, query '%s'", query.Query)
+				return
+			}
+			if expect.MinRows != nil && expect.MaxRows != nil && *expect.MinRows > *expect.MaxRows {
+				err = fmt.Errorf("expect min_rows can't be bigger than max_rows, query '%s'", query.Query)
+				return
+			}
+		}
+		if query.Name == "" {
+			continue
+		}
+		if queryNames[query.Name] {
+			err = fmt.Errorf("query name '%s' is used more than once", query.Name)
+			return
+		}
+		queryNames[query.Name] = true
+	}
+
+	for i := range yamlConfig.Parameters.TermSources {
+		if err = checkTermSourceConfig(&yamlConfig.Parameters.TermSources[i], queryNames); err != nil {
+			return
+		}
+		if file := yamlConfig.Parameters.TermSources[i].File; file != "" {
+			yamlConfig.Parameters.TermSources[i].File = relativeToYaml(yamlFile, file)
+		}
+	}
+	termSourceNames := map[string]bool{}
+	for _, source := range yamlConfig.Parameters.TermSources {
+		if termSourceNames[source.Name] {
+			err = fmt.Errorf("term source name '%s' is used more than once", source.Name)
+			return
+		}
+		termSourceNames[source.Name] = true
+	}
+
+	if yamlConfig.DBConfig.Generate != nil {
+		if yamlConfig.DBConfig.Dataset != nil {
+			err = errors.New("db_config can't have both a dataset and a generate section")
+			return
+		}
+		if err = checkGenerateConfig(yamlConfig.DBConfig.Generate); err != nil {
+			return
+		}
+	}
+
+	if dataset := yamlConfig.DBConfig.Dataset; dataset != nil && yamlConfig.DBConfig.DatasetRegistry != "" && registryDatasetReference(dataset) {
+		dataset.Name, dataset.Path = dataset.Path, ""
+	}
+	if dataset := yamlConfig.DBConfig.Dataset; dataset != nil && dataset.Name == "" && dataset.Version != "" {
+		err = errors.New("dataset version requires a name")
+		return
+	}
+	if dataset := yamlConfig.DBConfig.Dataset; dataset != nil && dataset.Name != "" {
+		if yamlConfig.DBConfig.DatasetRegistry == "" {
+			err = fmt.Errorf("dataset %s requires a dataset_registry", dataset.Name)
+			return
+		}
+		var registry *DatasetRegistry
+		if registry, err = loadDatasetRegistry(relativeToYaml(yamlFile, yamlConfig.DBConfig.DatasetRegistry)); err != nil {
+			return
+		}
+		var loadTimeoutSecs int
+		if loadTimeoutSecs, err = resolveRegistryDataset(dataset, registry); err != nil {
+			return
+		}
+		if yamlConfig.DBConfig.DatasetLoadTimeoutSecs == 0 {
+			yamlConfig.DBConfig.DatasetLoadTimeoutSecs = loadTimeoutSecs
+		}
+	}
+
+	if dataset := yamlConfig.DBConfig.Dataset; dataset != nil {
+		if (dataset.Path == "") == (dataset.Csv == nil) {
+			err = errors.New("dataset requires either a path or a csv section")
+			return
+		}
+		if dataset.Sha256 != "" && !sha256Pattern.MatchString(dataset.Sha256) {
+			err = errors.New("dataset sha256 must be 64 hexadecimal characters")
+			return
+		}
+		if dataset.Path == "" && (dataset.Nodes != nil || dataset.Edges != nil) {
+			err = errors.New("dataset nodes and edges are only verified for RDB datasets")
+			return
+		}
+		if dataset.Identity == nil && dataset.Path != "" {
+			dataset.Identity = &DatasetIdentity{Path: dataset.Path, Sha256: dataset.Sha256}
+		}
+		if dataset.Csv != nil {
+			if err = checkCsvDatasetConfig(dataset.Csv); err != nil {
+				return
+			}
+			for i := range dataset.Csv.Nodes {
+				dataset.Csv.Nodes[i].File = relativeToYaml(yamlFile, dataset.Csv.Nodes[i].File)
+			}
+			for i := range dataset.Csv.Relationships {
+				dataset.Csv.Relationships[i].File = relativeToYaml(yamlFile, dataset.Csv.Relationships[i].File)
+			}
+		}
+	}
+
 	if yamlConfig.DBConfig.DatasetLoadTimeoutSecs == 0 {
 		yamlConfig.DBConfig.DatasetLoadTimeoutSecs = 180
 	}
@@ -107,23 +426,325 @@ func parseYaml(yamlFile string) (yamlConfig YamlConfig, err error) {
 		*yamlConfig.Parameters.RandomSeed = 12345
 	}
 
+	if yamlConfig.Exporter != nil {
+		if err = checkExporterConfig(yamlConfig.Exporter, yamlConfig.DBConfig.Host, yamlConfig.DBConfig.Port); err != nil {
+			return
+		}
+	}
+
 	return
 }
 
+// checkExporterConfig validates the exporter and fills its defaults. The exporter must not write into the database
+// under test, so its address has no default and can't be the database one.
+func checkExporterConfig(exporter *ExporterConfig, dbHost string, dbPort int) error {
+	if !exporter.TimeSeries && exporter.Graph == "" {
+		return errors.New("exporter requires timeseries to be enabled and/or a graph to be set")
+	}
+	if exporter.Host == "" || exporter.Port == 0 {
+		return errors.New("exporter requires a host and a port, separate from the database under test")
+	}
+	if exporter.Port == dbPort && loopbackHost(exporter.Host) == loopbackHost(dbHost) {
+		return fmt.Errorf("exporter address %s:%d is the database under test, use a separate server", exporter.Host, exporter.Port)
+	}
+	if exporter.KeyPrefix == "" {
+		exporter.KeyPrefix = "falkordb-benchmark"
+	}
+	return nil
+}
+
+// loopbackHost names every loopback address alike, so that they compare equal
+func loopbackHost(host string) string {
+	host = strings.ToLower(host)
+	if host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.") {
+		return "localhost"
+	}
+	return host
+}
+
+// checkTermSourceConfig validates a term source and fills its defaults
+func checkTermSourceConfig(source *TermSourceConfig, queryNames map[string]bool) error {
+	if source.Name == "" || (source.File == "") == (source.Cypher == "") {
+		return errors.New("term sources require a name and either a file or a cypher query")
+	}
+	if source.SampleSize < 0 {
+		return fmt.Errorf("term source '%s' sample_size must not be negative", source.Name)
+	}
+	// the returned columns of a query are not known in advance, and a placeholder such as id is also a substring of
+	// most queries: cypher sources declare their columns and how each of them is used
+	if source.Cypher != "" && len(source.Columns) == 0 {
+		return fmt.Errorf("term source '%s' requires columns with a cypher query", source.Name)
+	}
+	if source.Format != "" && source.Format != termFormatCsv && source.Format != termFormatTsv && source.Format != termFormatJsonl {
+		return fmt.Errorf("term source '%s' format must be one of csv, tsv or jsonl", source.Name)
+	}
+	if source.Mode == "" {
+		source.Mode = termModeSeq
+	}
+	if source.Mode != termModeSeq && source.Mode != termModeRand && source.Mode != termModeZipf && source.Mode != termModePartitioned {
+		return fmt.Errorf("term source '%s' mode must be one of seq, rand, zipf or partitioned", source.Name)
+	}
+	if source.ZipfS == 0 {
+		source.ZipfS = defaultZipfS
+	}
+	if source.ZipfS <= 1 {
+		return fmt.Errorf("term source '%s' zipf_s must be bigger than 1", source.Name)
+	}
+	for _, queryName := range source.Queries {
+		if !queryNames[queryName] {
+			return fmt.Errorf("term source '%s' is bound to the unknown query '%s'", source.Name, queryName)
+		}
+	}
+	for i := range source.Columns {
+		column := &source.Columns[i]
+		if column.Name == "" {
+			return fmt.Errorf("term source '%s' columns require a name", source.Name)
+		}
+		if column.Placeholder == "" && column.Param == "" {
+			if source.Cypher != "" {
+				return fmt.Errorf("term source '%s' column '%s' requires a placeholder or a param with a cypher query", source.Name, column.Name)
+			}
+			column.Placeholder = column.Name
+		}
+		if column.Type == "" {
+			column.Type = termTypeString
+		}
+		if column.Type != termTypeString && column.Type != termTypeInt && column.Type != termTypeFloat && column.Type != termTypeBool {
+			return fmt.Errorf("term source '%s' column '%s' type must be one of string, int, float or bool", source.Name, column.Name)
+		}
+	}
+	return nil
+}
+
+// orderedQueries returns the write queries followed by the read-only ones, in the same order used by convertQueries
+func orderedQueries(queries []Query, roQueries []Query) []Query {
+	return append(append([]Query{}, queries...), roQueries...)
+}
+
 func convertQueries(
-	queries []Query, roQueries []Query) (allQueries []string, queryIsRO []bool, queryRates []float64) {
+	queries []Query, roQueries []Query) (allQueries []string, queryIsRO []bool, queryRates []float64, queryNames []string) {
 
 	for _, query := range queries {
 		allQueries = append(allQueries, query.Query)
 		queryIsRO = append(queryIsRO, false)
 		queryRates = append(queryRates, query.Ratio)
+		queryNames = append(queryNames, query.Name)
 	}
 
 	for _, query := range roQueries {
 		allQueries = append(allQueries, query.Query)
 		queryIsRO = append(queryIsRO, true)
 		queryRates = append(queryRates, query.Ratio)
+		queryNames = append(queryNames, query.Name)
+	}
+
+	return allQueries, queryIsRO, queryRates, queryNames
+}
+
+// generateQueryIds returns a stable identifier per query. Named queries use their name, the others
+// are identified by a hash of their whitespace normalized text and read-only flag,
+// so that reformatting the YAML does not change the identifier.
+// Identical queries within the same list get a numeric suffix.
+func generateQueryIds(allQueries []string, queryIsRO []bool, queryNames []string) []string {
+	ids := make([]string, len(allQueries))
+	used := map[string]bool{}
+	for i, query := range allQueries {
+		id := queryNames[i]
+		if id == "" {
+			kind := "rw"
+			if queryIsRO[i] {
+				kind = "ro"
+			}
+			sum := sha256.Sum256([]byte(kind + ":" + strings.Join(strings.Fields(query), " ")))
+			id = "q-" + hex.EncodeToString(sum[:])[:12]
+		}
+		candidate := id
+		for suffix := 2; used[candidate]; suffix++ {
+			candidate = fmt.Sprintf("%s-%d", id, suffix)
+		}
+		used[candidate] = true
+		ids[i] = candidate
+	}
+	return ids
+}
+
+// generateQueryLabels returns the name of each query, falling back to the query text for unnamed queries
+func generateQueryLabels(allQueries []string, queryNames []string) []string {
+	labels := make([]string, len(allQueries))
+	for i, query := range allQueries {
+		labels[i] = query
+		if queryNames[i] != "" {
+			labels[i] = queryNames[i]
+		}
+	}
+	return labels
+}
+
+// checkGenerateConfig validates a synthetic graph model and fills its defaults
+func checkGenerateConfig(generate *GenerateConfig) error {
+	if generate.BatchSize == 0 {
+		generate.BatchSize = defaultGenerateBatchSize
+	}
+	if generate.BatchSize < 0 {
+		return errors.New("generate batch_size must be positive")
+	}
+	if len(generate.Nodes) == 0 {
+		return errors.New("generate requires at least one node label")
+	}
+	labels := map[string]bool{}
+	for i := range generate.Nodes {
+		nodes := &generate.Nodes[i]
+		if nodes.Label == "" || nodes.Count <= 0 {
+			return errors.New("generate nodes require a label and a positive count")
+		}
+		if labels[nodes.Label] {
+			return fmt.Errorf("generate label '%s' is declared more than once", nodes.Label)
+		}
+		labels[nodes.Label] = true
+		if err := checkGenerateProperties(nodes.Properties, nodes.Label); err != nil {
+			return err
+		}
+	}
+	for i := range generate.Relationships {
+		relationship := &generate.Relationships[i]
+		if relationship.Type == "" || !labels[relationship.From] || !labels[relationship.To] {
+			return fmt.Errorf("generate relationship '%s' requires a type, and from and to labels declared in nodes", relationship.Type)
+		}
+		if relationship.Distribution == "" {
+			relationship.Distribution = degreeUniform
+		}
+		switch relationship.Distribution {
+		case degreeUniform, degreePowerLaw:
+			if relationship.Degree <= 0 {
+				return fmt.Errorf("generate relationship '%s' requires a positive degree", relationship.Type)
+			}
+		case degreeErdosRenyi:
+			if relationship.Degree <= 0 && relationship.Probability <= 0 {
+				return fmt.Errorf("generate relationship '%s' requires a positive degree or probability", relationship.Type)
+			}
+			if relationship.Probability > 1 {
+				return fmt.Errorf("generate relationship '%s' probability must not be bigger than 1", relationship.Type)
+			}
+		default:
+			return fmt.Errorf("generate relationship '%s' distribution must be one of uniform, power_law or erdos_renyi", relationship.Type)
+		}
+		if relationship.Exponent == 0 {
+			relationship.Exponent = defaultPowerLawExponent
+		}
+		if relationship.Exponent <= 2 {
+			return fmt.Errorf("generate relationship '%s' exponent must be bigger than 2", relationship.Type)
+		}
+		if err := checkGenerateProperties(relationship.Properties, relationship.Type); err != nil {
+			return err
+		}
+	}
+	for _, index := range generate.Indices {
+		if (index.Label == "") == (index.Type == "") || len(index.Properties) == 0 {
+			return errors.New("generate indices require either a label or a type, and properties")
+		}
+	}
+	return nil
+}
+
+func checkGenerateProperties(properties []GenerateProperty, owner string) error {
+	for i := range properties {
+		property := &properties[i]
+		if property.Name == "" {
+			return fmt.Errorf("generate properties of '%s' require a name", owner)
+		}
+		if property.Type == "" {
+			property.Type = propertyTypeId
+		}
+		switch property.Type {
+		case propertyTypeId, propertyTypeBool:
+		case propertyTypeInt, propertyTypeFloat:
+			if property.Min == 0 && property.Max == 0 {
+				property.Max = defaultPropertyMax
+			}
+			if property.Min > property.Max {
+				return fmt.Errorf("generate property '%s' of '%s' min can't be bigger than max", property.Name, owner)
+			}
+		case propertyTypeString:
+			if property.Length == 0 {
+				property.Length = defaultPropertyLength
+			}
+			if property.Length < 0 {
+				return fmt.Errorf("generate property '%s' of '%s' length must be positive", property.Name, owner)
+			}
+		default:
+			return fmt.Errorf("generate property '%s' of '%s' type must be one of id, int, float, string or bool", property.Name, owner)
+		}
+	}
+	return nil
+}
+
+// relativeToYaml resolves a relative file path against the directory of the YAML file
+func relativeToYaml(yamlFile, file string) string {
+	if filepath.IsAbs(file) {
+		return file
 	}
+	return filepath.Join(filepath.Dir(yamlFile), file)
+}
 
-	return allQueries, queryIsRO, queryRates
+// checkCsvDatasetConfig validates a CSV dataset and fills its defaults
+func checkCsvDatasetConfig(csv *CsvDatasetConfig) error {
+	if csv.BatchSize == 0 {
+		csv.BatchSize = defaultGenerateBatchSize
+	}
+	if csv.Concurrency == 0 {
+		csv.Concurrency = defaultCsvConcurrency
+	}
+	if csv.BatchSize < 0 || csv.Concurrency < 0 {
+		return errors.New("csv dataset batch_size and concurrency must be positive")
+	}
+	if len(csv.Nodes) == 0 {
+		return errors.New("csv dataset requires at least one nodes file")
+	}
+	labels := map[string]bool{}
+	for i := range csv.Nodes {
+		nodes := &csv.Nodes[i]
+		if nodes.Label == "" || nodes.File == "" {
+			return errors.New("csv dataset nodes require a label and a file")
+		}
+		if labels[nodes.Label] {
+			return fmt.Errorf("csv dataset label '%s' is declared more than once", nodes.Label)
+		}
+		labels[nodes.Label] = true
+		if nodes.Id == "" {
+			nodes.Id = "id"
+		}
+		if err := checkCsvTypes(nodes.Types, nodes.File); err != nil {
+			return err
+		}
+	}
+	for i := range csv.Relationships {
+		relationship := &csv.Relationships[i]
+		if relationship.Type == "" || relationship.File == "" || !labels[relationship.From] || !labels[relationship.To] {
+			return fmt.Errorf("csv dataset relationship '%s' requires a type, a file, and from and to labels declared in nodes", relationship.Type)
+		}
+		if relationship.FromColumn == "" {
+			relationship.FromColumn = "src"
+		}
+		if relationship.ToColumn == "" {
+			relationship.ToColumn = "dst"
+		}
+		if err := checkCsvTypes(relationship.Types, relationship.File); err != nil {
+			return err
+		}
+	}
+	for _, index := range csv.Indices {
+		if (index.Label == "") == (index.Type == "") || len(index.Properties) == 0 {
+			return errors.New("csv dataset indices require either a label or a type, and properties")
+		}
+	}
+	return nil
+}
+
+func checkCsvTypes(types map[string]string, file string) error {
+	for column, columnType := range types {
+		if columnType != termTypeString && columnType != termTypeInt && columnType != termTypeFloat && columnType != termTypeBool {
+			return fmt.Errorf("csv dataset column '%s' of %s type must be one of string, int, float or bool", column, file)
+		}
+	}
+	return nil
 }
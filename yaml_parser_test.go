@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_generateQueryIds(t *testing.T) {
+	type args struct {
+		allQueries []string
+		queryIsRO  []bool
+		queryNames []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{"named", args{[]string{"CREATE (n)", "MATCH (n) RETURN n"}, []bool{false, true}, []string{"create", "match"}}, []string{"create", "match"}},
+		{"same-query-rw-and-ro", args{[]string{"MATCH (n) RETURN n", "MATCH (n) RETURN n"}, []bool{false, true}, []string{"", ""}}, []string{"q-045b562614cf", "q-4b65126b6ecd"}},
+		{"whitespace-insensitive", args{[]string{"MATCH (n)\n  RETURN   n "}, []bool{true}, []string{""}}, []string{"q-4b65126b6ecd"}},
+		{"duplicated-query", args{[]string{"CREATE (n)", "CREATE (n)"}, []bool{false, false}, []string{"", ""}}, []string{"q-66d950f16369", "q-66d950f16369-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateQueryIds(tt.args.allQueries, tt.args.queryIsRO, tt.args.queryNames); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("generateQueryIds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkTermSourceConfig(t *testing.T) {
+	queryNames := map[string]bool{"q1": true}
+	tests := []struct {
+		name    string
+		source  TermSourceConfig
+		wantErr bool
+	}{
+		{"defaults", TermSourceConfig{Name: "s", File: "terms.csv", Columns: []TermColumn{{Name: "id"}}}, false},
+		{"cypher", TermSourceConfig{Name: "s", Cypher: "MATCH (n:User) RETURN n.id AS id", SampleSize: 100, Columns: []TermColumn{{Name: "id", Placeholder: "id"}}}, false},
+		{"cypher-without-columns", TermSourceConfig{Name: "s", Cypher: "MATCH (n:User) RETURN n.id AS id"}, true},
+		{"cypher-bare-column", TermSourceConfig{Name: "s", Cypher: "MATCH (n:User) RETURN n.id AS id", Columns: []TermColumn{{Name: "id"}}}, true},
+		{"missing-file", TermSourceConfig{Name: "s"}, true},
+		{"file-and-cypher", TermSourceConfig{Name: "s", File: "terms.csv", Cypher: "MATCH (n) RETURN n.id"}, true},
+		{"negative-sample", TermSourceConfig{Name: "s", Cypher: "MATCH (n) RETURN n.id AS id", SampleSize: -1, Columns: []TermColumn{{Name: "id", Param: "id"}}}, true},
+		{"unknown-format", TermSourceConfig{Name: "s", File: "terms.xml", Format: "xml"}, true},
+		{"unknown-mode", TermSourceConfig{Name: "s", File: "terms.csv", Mode: "shuffle"}, true},
+		{"zipf-exponent", TermSourceConfig{Name: "s", File: "terms.csv", Mode: termModeZipf, ZipfS: 0.5}, true},
+		{"unknown-query", TermSourceConfig{Name: "s", File: "terms.csv", Queries: []string{"q2"}}, true},
+		{"unknown-type", TermSourceConfig{Name: "s", File: "terms.csv", Columns: []TermColumn{{Name: "id", Type: "date"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTermSourceConfig(&tt.source, queryNames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkTermSourceConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (tt.source.Mode != termModeSeq || tt.source.ZipfS != defaultZipfS || tt.source.Columns[0].Placeholder != "id" || tt.source.Columns[0].Type != termTypeString) {
+				t.Errorf("checkTermSourceConfig() defaults = %+v", tt.source)
+			}
+		})
+	}
+}
+
+func Test_checkGenerateConfig(t *testing.T) {
+	users := GenerateNodes{Label: "User", Count: 10, Properties: []GenerateProperty{{Name: "id"}, {Name: "age", Type: propertyTypeInt}}}
+	tests := []struct {
+		name     string
+		generate GenerateConfig
+		wantErr  bool
+	}{
+		{"defaults", GenerateConfig{Nodes: []GenerateNodes{users}, Relationships: []GenerateRelationship{{Type: "FOLLOWS", From: "User", To: "User", Degree: 2}}}, false},
+		{"no-nodes", GenerateConfig{}, true},
+		{"no-count", GenerateConfig{Nodes: []GenerateNodes{{Label: "User"}}}, true},
+		{"duplicate-label", GenerateConfig{Nodes: []GenerateNodes{users, users}}, true},
+		{"unknown-label", GenerateConfig{Nodes: []GenerateNodes{users}, Relationships: []GenerateRelationship{{Type: "LIKES", From: "User", To: "Post", Degree: 2}}}, true},
+		{"no-degree", GenerateConfig{Nodes: []GenerateNodes{users}, Relationships: []GenerateRelationship{{Type: "FOLLOWS", From: "User", To: "User"}}}, true},
+		{"erdos-renyi-probability", GenerateConfig{Nodes: []GenerateNodes{users}, Relationships: []GenerateRelationship{{Type: "FOLLOWS", From: "User", To: "User", Distribution: degreeErdosRenyi, Probability: 0.1}}}, false},
+		{"power-law-exponent", GenerateConfig{Nodes: []GenerateNodes{users}, Relationships: []GenerateRelationship{{Type: "FOLLOWS", From: "User", To: "User", Distribution: degreePowerLaw, Degree: 2, Exponent: 1.5}}}, true},
+		{"unknown-distribution", GenerateConfig{Nodes: []GenerateNodes{users}, Relationships: []GenerateRelationship{{Type: "FOLLOWS", From: "User", To: "User", Distribution: "normal", Degree: 2}}}, true},
+		{"unknown-property-type", GenerateConfig{Nodes: []GenerateNodes{{Label: "User", Count: 1, Properties: []GenerateProperty{{Name: "at", Type: "date"}}}}}, true},
+		{"index-without-properties", GenerateConfig{Nodes: []GenerateNodes{users}, Indices: []GenerateIndex{{Label: "User"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGenerateConfig(&tt.generate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkGenerateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.name == "defaults" {
+				age := tt.generate.Nodes[0].Properties[1]
+				if tt.generate.BatchSize != defaultGenerateBatchSize || tt.generate.Nodes[0].Properties[0].Type != propertyTypeId || age.Max != defaultPropertyMax || tt.generate.Relationships[0].Distribution != degreeUniform {
+					t.Errorf("checkGenerateConfig() defaults = %+v", tt.generate)
+				}
+			}
+		})
+	}
+}
+
+func Test_checkCsvDatasetConfig(t *testing.T) {
+	users := CsvNodesFile{Label: "User", File: "users.csv"}
+	tests := []struct {
+		name    string
+		csv     CsvDatasetConfig
+		wantErr bool
+	}{
+		{"defaults", CsvDatasetConfig{Nodes: []CsvNodesFile{users}, Relationships: []CsvRelationshipFile{{Type: "FOLLOWS", File: "follows.csv", From: "User", To: "User"}}}, false},
+		{"no-nodes", CsvDatasetConfig{}, true},
+		{"no-file", CsvDatasetConfig{Nodes: []CsvNodesFile{{Label: "User"}}}, true},
+		{"duplicate-label", CsvDatasetConfig{Nodes: []CsvNodesFile{users, users}}, true},
+		{"unknown-label", CsvDatasetConfig{Nodes: []CsvNodesFile{users}, Relationships: []CsvRelationshipFile{{Type: "LIKES", File: "likes.csv", From: "User", To: "Post"}}}, true},
+		{"unknown-type", CsvDatasetConfig{Nodes: []CsvNodesFile{{Label: "User", File: "users.csv", Types: map[string]string{"at": "date"}}}}, true},
+		{"negative-concurrency", CsvDatasetConfig{Concurrency: -1, Nodes: []CsvNodesFile{users}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCsvDatasetConfig(&tt.csv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkCsvDatasetConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.name == "defaults" {
+				relationship := tt.csv.Relationships[0]
+				if tt.csv.BatchSize != defaultGenerateBatchSize || tt.csv.Concurrency != defaultCsvConcurrency || tt.csv.Nodes[0].Id != "id" || relationship.FromColumn != "src" || relationship.ToColumn != "dst" {
+					t.Errorf("checkCsvDatasetConfig() defaults = %+v", tt.csv)
+				}
+			}
+		})
+	}
+}
+
+func Test_checkExporterConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter ExporterConfig
+		wantErr  bool
+	}{
+		{"separate", ExporterConfig{Host: "metrics-host", Port: 6379, TimeSeries: true}, false},
+		{"other-port", ExporterConfig{Host: "localhost", Port: 6380, Graph: "history"}, false},
+		{"no-address", ExporterConfig{TimeSeries: true}, true},
+		{"no-port", ExporterConfig{Host: "metrics-host", TimeSeries: true}, true},
+		{"database-under-test", ExporterConfig{Host: "127.0.0.1", Port: 6379, TimeSeries: true}, true},
+		{"nothing-exported", ExporterConfig{Host: "metrics-host", Port: 6379}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExporterConfig(&tt.exporter, "localhost", 6379)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkExporterConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.exporter.KeyPrefix != "falkordb-benchmark" {
+				t.Errorf("checkExporterConfig() key prefix = %v, want the default one", tt.exporter.KeyPrefix)
+			}
+		})
+	}
+}